@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The fields set by logzum.DefaultConfig and the logging middlewares.
+const (
+	timeField      = "time"
+	levelField     = "level"
+	messageField   = "message"
+	tokenField     = "bztoken"
+	requestIDField = "requestID"
+)
+
+// completionMessages end the group of a request.
+var completionMessages = map[string]bool{
+	"finished http call":      true,
+	"finished unary call":     true,
+	"finished streaming call": true,
+}
+
+// entry is a received line.
+type entry struct {
+	raw []byte
+	// fields is the decoded line, nil if it is not a JSON object
+	fields   map[string]interface{}
+	received time.Time
+}
+
+func parseEntry(line []byte, received time.Time) *entry {
+	e := &entry{raw: append([]byte(nil), bytes.TrimSpace(line)...), received: received}
+	var fields map[string]interface{}
+	if json.Unmarshal(e.raw, &fields) == nil {
+		e.fields = fields
+	}
+	return e
+}
+
+// field returns the field key formatted as a string, the message of a plain text line is the line itself.
+func (e *entry) field(key string) (string, bool) {
+	if e.fields == nil {
+		if key == messageField {
+			return string(e.raw), true
+		}
+		return "", false
+	}
+	v, ok := e.fields[key]
+	if !ok {
+		return "", false
+	}
+	return format(v), true
+}
+
+func (e *entry) str(key string) string {
+	v, _ := e.field(key)
+	return v
+}
+
+func (e *entry) requestID() string {
+	return e.str(requestIDField)
+}
+
+func (e *entry) completes() bool {
+	return completionMessages[e.str(messageField)]
+}
+
+// time returns the time of the entry, or when it was received.
+func (e *entry) time() time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, e.str(timeField)); err == nil {
+		return t
+	}
+	return e.received
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// filter is a field expression an entry must match to be printed:
+//
+//	key          the field is present
+//	!key         the field is missing
+//	key=value    key!=value
+//	key~regexp   key!~regexp
+//	key>value    key>=value    key<value    key<=value
+//
+// Values are compared as numbers when both sides are numbers. The level field is compared by severity,
+// so level>=warn matches warning, error, fatal and panic entries.
+type filter struct {
+	key string
+	op  string
+	val string
+	re  *regexp.Regexp
+}
+
+// operators are ordered so that the longer ones are matched first.
+var operators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+func parseFilter(expr string) (filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return filter{}, fmt.Errorf("empty filter")
+	}
+
+	// the first operator found in the expression, the value may contain other ones
+	at, op := -1, ""
+	for _, o := range operators {
+		if i := strings.Index(expr, o); i > 0 && (at < 0 || i < at || (i == at && len(o) > len(op))) {
+			at, op = i, o
+		}
+	}
+	if at < 0 {
+		if strings.HasPrefix(expr, "!") {
+			return filter{key: expr[1:], op: "!"}, nil
+		}
+		return filter{key: expr}, nil
+	}
+
+	f := filter{
+		key: strings.TrimSpace(expr[:at]),
+		op:  op,
+		val: strings.TrimSpace(expr[at+len(op):]),
+	}
+	switch op {
+	case "~", "!~":
+		re, err := regexp.Compile(f.val)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid filter %q: %v", expr, err)
+		}
+		f.re = re
+	case ">", ">=", "<", "<=":
+		if f.key == levelField {
+			if _, err := logrus.ParseLevel(f.val); err != nil {
+				return filter{}, fmt.Errorf("invalid filter %q: %v", expr, err)
+			}
+		}
+	}
+	return f, nil
+}
+
+func (f filter) match(e *entry) bool {
+	v, ok := e.field(f.key)
+	switch f.op {
+	case "":
+		return ok
+	case "!":
+		return !ok
+	case "!=":
+		return !ok || !equal(v, f.val)
+	case "!~":
+		return !ok || !f.re.MatchString(v)
+	}
+	if !ok {
+		return false
+	}
+
+	switch f.op {
+	case "=":
+		return equal(v, f.val)
+	case "~":
+		return f.re.MatchString(v)
+	}
+
+	c, ok := f.compare(v)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	default:
+		return c <= 0
+	}
+}
+
+// compare returns the sign of v minus the filter value.
+func (f filter) compare(v string) (int, bool) {
+	if f.key == levelField {
+		l, err := logrus.ParseLevel(v)
+		if err != nil {
+			return 0, false
+		}
+		want, _ := logrus.ParseLevel(f.val)
+		// logrus levels go from the most to the least severe
+		return int(want) - int(l), true
+	}
+	a, errA := strconv.ParseFloat(v, 64)
+	b, errB := strconv.ParseFloat(f.val, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a > b:
+			return 1, true
+		case a < b:
+			return -1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(v, f.val), true
+}
+
+func equal(v, want string) bool {
+	if v == want {
+		return true
+	}
+	a, errA := strconv.ParseFloat(v, 64)
+	b, errB := strconv.ParseFloat(want, 64)
+	return errA == nil && errB == nil && a == b
+}
+
+// filters is a repeatable flag, an entry is printed when it matches all of them.
+type filters []filter
+
+func (fs *filters) String() string {
+	return fmt.Sprint(len(*fs), " filters")
+}
+
+func (fs *filters) Set(expr string) error {
+	f, err := parseFilter(expr)
+	if err != nil {
+		return err
+	}
+	*fs = append(*fs, f)
+	return nil
+}
+
+func (fs filters) match(e *entry) bool {
+	for _, f := range fs {
+		if !f.match(e) {
+			return false
+		}
+	}
+	return true
+}
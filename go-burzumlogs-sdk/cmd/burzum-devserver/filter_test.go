@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	e := parseEntry([]byte(`{"level":"warning","message":"finished http call","http.status":404,"http.path":"/orders","user":{"id":1}}`), time.Now())
+
+	for expr, want := range map[string]bool{
+		"http.status":               true,
+		"!http.status":              false,
+		"!missing":                  true,
+		"http.status=404":           true,
+		"http.status=404.0":         true,
+		"http.status!=404":          false,
+		"missing!=1":                true,
+		"http.status>=400":          true,
+		"http.status>404":           false,
+		"http.status<500":           true,
+		"http.status<=403":          false,
+		"http.path~^/ord":           true,
+		"http.path!~^/ord":          false,
+		"message~finished (http|g)": true,
+		`user~"id":1`:               true,
+		"level>=warn":               true,
+		"level>=error":              false,
+		"level<info":                false,
+		"level>info":                true,
+		"level<=warning":            true,
+		"level=warning":             true,
+		"missing>1":                 false,
+		"http.path>/a":              true,
+	} {
+		f, err := parseFilter(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, f.match(e), expr)
+	}
+
+	text := parseEntry([]byte(`time="now" level=info msg=plain`), time.Now())
+	var fs filters
+	require.NoError(t, fs.Set("message~msg=plain"))
+	assert.True(t, fs.match(text))
+	require.NoError(t, fs.Set("level"))
+	assert.False(t, fs.match(text), "plain text lines only have a message")
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{"", "message~(", "level>=loud"} {
+		_, err := parseFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,72 @@
+// Command burzum-devserver is a local stand-in for Burzum, it receives the lines sent by the logzum hook and
+// pretty-prints them, colourised by level and grouped by request ID.
+//
+//	burzum-devserver -addr localhost:5030 -filter 'level>=info' -filter 'http.status>=400' -out ./logs
+//
+// Point logzum.Config.Host at -addr. Lines are also accepted as newline delimited JSON posted to -http.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var fs filters
+	addr := flag.String("addr", "localhost:5030", "TCP address of the line protocol")
+	httpAddr := flag.String("http", "", "HTTP address accepting newline delimited JSON, disabled if empty")
+	out := flag.String("out", "", "directory of the NDJSON files storing every received entry, disabled if empty")
+	groupTimeout := flag.Duration("group-timeout", 2*time.Second, "print the entries of a request after this inactivity if its completion line is missing, 0 disables grouping")
+	noColor := flag.Bool("no-color", os.Getenv("NO_COLOR") != "", "disable colours")
+	flag.Var(&fs, "filter", "print the entries matching `expr`: key, !key, key=value, key!=value, key~regexp, key!~regexp, key>value, key>=value, key<value, key<=value; repeatable")
+	flag.Parse()
+
+	p := newPrinter(os.Stdout, !*noColor, fs, *groupTimeout)
+	s := &server{printer: p}
+	if *out != "" {
+		st, err := newStore(*out)
+		if err != nil {
+			log.Fatalf("burzum-devserver: %v", err)
+		}
+		defer st.Close()
+		s.store = st
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("burzum-devserver: %v", err)
+	}
+	log.Printf("burzum-devserver: listening on %s", ln.Addr())
+	go func() {
+		log.Fatalf("burzum-devserver: %v", s.serveTCP(ln))
+	}()
+
+	if *httpAddr != "" {
+		log.Printf("burzum-devserver: accepting HTTP on %s", *httpAddr)
+		go func() {
+			log.Fatalf("burzum-devserver: %v", http.ListenAndServe(*httpAddr, s))
+		}()
+	}
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if *groupTimeout > 0 {
+				p.expire(now)
+			}
+		case <-sigC:
+			p.flush()
+			return
+		}
+	}
+}
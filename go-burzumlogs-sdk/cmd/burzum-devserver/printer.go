@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	faint = "\x1b[2m"
+)
+
+var levelColors = map[logrus.Level]string{
+	logrus.DebugLevel: "\x1b[90m",
+	logrus.InfoLevel:  "\x1b[36m",
+	logrus.WarnLevel:  "\x1b[33m",
+	logrus.ErrorLevel: "\x1b[31m",
+	logrus.FatalLevel: "\x1b[35m",
+	logrus.PanicLevel: "\x1b[35m",
+}
+
+// hiddenFields are not printed with the other fields, they are either part of the line or secret.
+var hiddenFields = map[string]bool{
+	timeField:    true,
+	levelField:   true,
+	messageField: true,
+	tokenField:   true,
+}
+
+// printer pretty-prints the entries matching its filters, grouping the ones of a request until its completion
+// line or until no entry of the request arrives for groupTimeout.
+type printer struct {
+	mu sync.Mutex
+
+	out io.Writer
+
+	color bool
+
+	filters filters
+
+	groupTimeout time.Duration
+
+	groups map[string]*group
+}
+
+type group struct {
+	entries []*entry
+	last    time.Time
+}
+
+func newPrinter(out io.Writer, color bool, fs filters, groupTimeout time.Duration) *printer {
+	return &printer{
+		out:          out,
+		color:        color,
+		filters:      fs,
+		groupTimeout: groupTimeout,
+		groups:       make(map[string]*group),
+	}
+}
+
+func (p *printer) add(e *entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := e.requestID()
+	if p.groupTimeout <= 0 || id == "" {
+		if p.filters.match(e) {
+			p.print(e, "")
+		}
+		return
+	}
+
+	g, ok := p.groups[id]
+	if !ok {
+		g = &group{}
+		p.groups[id] = g
+	}
+	g.entries = append(g.entries, e)
+	g.last = e.received
+	if e.completes() {
+		p.printGroup(id, g)
+	}
+}
+
+// expire prints the groups without entries since groupTimeout before now.
+func (p *printer) expire(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range p.sortedGroups() {
+		if g := p.groups[id]; now.Sub(g.last) >= p.groupTimeout {
+			p.printGroup(id, g)
+		}
+	}
+}
+
+// flush prints all the pending groups.
+func (p *printer) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range p.sortedGroups() {
+		p.printGroup(id, p.groups[id])
+	}
+}
+
+// sortedGroups returns the pending request IDs, oldest first.
+func (p *printer) sortedGroups() []string {
+	ids := make([]string, 0, len(p.groups))
+	for id := range p.groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return p.groups[ids[i]].entries[0].received.Before(p.groups[ids[j]].entries[0].received)
+	})
+	return ids
+}
+
+func (p *printer) printGroup(id string, g *group) {
+	delete(p.groups, id)
+
+	var matched []*entry
+	for _, e := range g.entries {
+		if p.filters.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	header := fmt.Sprintf("── request %s (%d of %d entries) ", id, len(matched), len(g.entries))
+	if n := 60 - len([]rune(header)); n > 0 {
+		header += strings.Repeat("─", n)
+	}
+	fmt.Fprintln(p.out, p.paint(bold, header))
+	for _, e := range matched {
+		p.print(e, "  ")
+	}
+}
+
+func (p *printer) print(e *entry, indent string) {
+	var b strings.Builder
+	b.WriteString(indent)
+	b.WriteString(p.paint(faint, e.time().Local().Format("15:04:05.000")))
+	b.WriteByte(' ')
+
+	if e.fields == nil {
+		b.WriteString(string(e.raw))
+		fmt.Fprintln(p.out, b.String())
+		return
+	}
+
+	name := e.str(levelField)
+	level, err := logrus.ParseLevel(name)
+	if err == nil {
+		name = level.String()
+	}
+	b.WriteString(p.paint(levelColors[level], fmt.Sprintf("%-5.5s", strings.ToUpper(name))))
+	b.WriteByte(' ')
+	b.WriteString(e.str(messageField))
+
+	keys := make([]string, 0, len(e.fields))
+	for k := range e.fields {
+		// the request ID is in the group header
+		if hiddenFields[k] || (k == requestIDField && indent != "") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := e.str(k)
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		b.WriteString("  ")
+		b.WriteString(p.paint(faint, k+"="))
+		b.WriteString(v)
+	}
+	fmt.Fprintln(p.out, b.String())
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.color || color == "" {
+		return s
+	}
+	return color + s + reset
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(b *bytes.Buffer) []string {
+	var out []string
+	sc := bufio.NewScanner(strings.NewReader(b.String()))
+	for sc.Scan() {
+		out = append(out, sc.Text())
+	}
+	return out
+}
+
+func TestPrinterGroupsByRequest(t *testing.T) {
+	var b bytes.Buffer
+	p := newPrinter(&b, false, nil, time.Second)
+	now := time.Now()
+
+	p.add(parseEntry([]byte(`{"level":"info","message":"loading","requestID":"a","bztoken":"secret"}`), now))
+	p.add(parseEntry([]byte(`{"level":"info","message":"loading","requestID":"b"}`), now))
+	p.add(parseEntry([]byte(`{"level":"info","message":"no request","n":1}`), now))
+	p.add(parseEntry([]byte(`{"level":"warning","message":"finished http call","requestID":"a","http.status":404}`), now))
+
+	out := lines(&b)
+	require.Len(t, out, 4)
+	assert.Contains(t, out[0], "INFO  no request  n=1")
+	assert.Contains(t, out[1], "── request a (2 of 2 entries)")
+	assert.Contains(t, out[2], "INFO  loading")
+	assert.NotContains(t, out[2], "secret", "the token is hidden")
+	assert.Contains(t, out[3], "WARNI finished http call  http.status=404")
+	assert.True(t, strings.HasPrefix(out[3], "  "))
+
+	p.expire(now.Add(500 * time.Millisecond))
+	assert.Len(t, lines(&b), 4, "b is still waiting for entries")
+	p.expire(now.Add(time.Second))
+	out = lines(&b)
+	require.Len(t, out, 6)
+	assert.Contains(t, out[4], "── request b (1 of 1 entries)")
+}
+
+func TestPrinterFiltersAndColors(t *testing.T) {
+	var b bytes.Buffer
+	var fs filters
+	require.NoError(t, fs.Set("level>=warn"))
+	p := newPrinter(&b, true, fs, 0)
+	now := time.Now()
+
+	p.add(parseEntry([]byte(`{"level":"info","message":"hidden","requestID":"a"}`), now))
+	p.add(parseEntry([]byte(`{"level":"error","message":"shown","requestID":"a","err":"a b"}`), now))
+
+	out := lines(&b)
+	require.Len(t, out, 1, "grouping is disabled")
+	assert.Contains(t, out[0], "\x1b[31mERROR\x1b[0m shown")
+	assert.Contains(t, out[0], `err=`+"\x1b[0m"+`"a b"`)
+	assert.Contains(t, out[0], "requestID=")
+}
+
+func TestPrinterFlush(t *testing.T) {
+	var b bytes.Buffer
+	var fs filters
+	require.NoError(t, fs.Set("message=kept"))
+	p := newPrinter(&b, false, fs, time.Minute)
+
+	p.add(parseEntry([]byte(`{"message":"dropped","requestID":"a"}`), time.Now()))
+	p.add(parseEntry([]byte(`{"message":"kept","requestID":"b"}`), time.Now()))
+	p.flush()
+
+	out := lines(&b)
+	require.Len(t, out, 2)
+	assert.Contains(t, out[0], "── request b (1 of 1 entries)")
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devserver")
+	require.NoError(t, err)
+	st, err := newStore(dir)
+	require.NoError(t, err)
+	defer st.Close()
+
+	var b bytes.Buffer
+	s := &server{printer: newPrinter(&b, false, nil, 0), store: st}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go s.serveTCP(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	fmt.Fprint(conn, `{"level":"info","message":"over tcp"}`+"\nplain text\n")
+	conn.Close()
+
+	web := httptest.NewServer(s)
+	defer web.Close()
+	res, err := http.Post(web.URL, "application/x-ndjson", strings.NewReader(`{"level":"info","message":"over http"}`+"\n"))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Eventually(t, func() bool {
+		s.printer.mu.Lock()
+		defer s.printer.mu.Unlock()
+		return len(lines(&b)) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	stored, err := ioutil.ReadFile(filepath.Join(dir, "burzum-"+time.Now().Format("2006-01-02")+".ndjson"))
+	require.NoError(t, err)
+	assert.Contains(t, string(stored), `{"level":"info","message":"over tcp"}`+"\n")
+	assert.Contains(t, string(stored), `{"message":"plain text"}`+"\n")
+	assert.Contains(t, string(stored), `{"level":"info","message":"over http"}`+"\n")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// server receives the lines of the logzum hook over TCP, and newline delimited JSON posted over HTTP.
+type server struct {
+	printer *printer
+
+	store *store
+}
+
+func (s *server) handle(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	e := parseEntry(line, time.Now())
+	if s.store != nil {
+		if err := s.store.write(e); err != nil {
+			log.Printf("burzum-devserver: unable to store entry: %v", err)
+		}
+	}
+	s.printer.add(e)
+}
+
+func (s *server) serveTCP(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		s.handle(line)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		s.handle(line)
+	}
+	w.WriteHeader(http.StatusOK)
+}
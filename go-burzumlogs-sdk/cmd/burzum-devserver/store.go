@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// store appends every received entry to a NDJSON file per day in dir, plain text lines are stored as
+// {"message": line}.
+type store struct {
+	mu sync.Mutex
+
+	dir string
+
+	day string
+
+	file *os.File
+}
+
+func newStore(dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &store{dir: dir}, nil
+}
+
+func (s *store) write(e *entry) error {
+	line := e.raw
+	if e.fields == nil {
+		line, _ = json.Marshal(map[string]string{messageField: string(e.raw)})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.open(e.received); err != nil {
+		return err
+	}
+	_, err := s.file.Write(append(line, '\n'))
+	return err
+}
+
+// open switches to the file of the day of t.
+func (s *store) open(t time.Time) error {
+	day := t.Format("2006-01-02")
+	if s.file != nil && s.day == day {
+		return nil
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, "burzum-"+day+".ndjson"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.file = nil
+		return err
+	}
+	s.file, s.day = f, day
+	return nil
+}
+
+func (s *store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// position is how far a file was shipped, ID identifies the file so a rotated one is read from the start.
+type position struct {
+	ID     uint64 `json:"id"`
+	Offset int64  `json:"offset"`
+}
+
+// checkpoints holds the positions of the tailed files, saved as JSON to path.
+type checkpoints struct {
+	mu sync.Mutex
+
+	path string
+
+	positions map[string]position
+}
+
+// loadCheckpoints reads the positions saved to path, none if the file doesn't exist or path is empty.
+func loadCheckpoints(path string) (*checkpoints, error) {
+	c := &checkpoints{path: path, positions: make(map[string]position)}
+	if path == "" {
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.positions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *checkpoints) get(file string) (position, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.positions[file]
+	return p, ok
+}
+
+func (c *checkpoints) set(file string, p position) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.positions[file] = p
+}
+
+// snapshot returns a copy of the current positions.
+func (c *checkpoints) snapshot() map[string]position {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := make(map[string]position, len(c.positions))
+	for k, v := range c.positions {
+		s[k] = v
+	}
+	return s
+}
+
+// save writes positions atomically, replacing the file.
+func (c *checkpoints) save(positions map[string]position) error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+// fileID is not available on windows, rotations are only detected while running, by os.SameFile.
+func fileID(info os.FileInfo) uint64 {
+	return 0
+}
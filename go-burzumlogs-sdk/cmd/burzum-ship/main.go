@@ -0,0 +1,251 @@
+// Command burzum-ship ships the JSON or plain text lines of files, or of stdin, to Burzum through the logzum hook,
+// with its connection, retry and token handling.
+//
+//	burzum-ship -config logzum.yaml -field app=batch -checkpoint /var/lib/burzum-ship.json /var/log/batch/*.log
+//	batch-job | burzum-ship -token $TOKEN
+//
+// Files are followed as they grow, across rotations, and their positions are checkpointed once the shipped lines
+// left the hook. Shipping waits while the hook queue is half full, and the checkpoints stop advancing once lines
+// were dropped or failed, so those are shipped again on restart. Without a config file the config is read from the BURZUM_ environment variables, see
+// logzum.ConfigFromEnv.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+// fieldsFlag is a repeatable key=value flag.
+type fieldsFlag map[string]interface{}
+
+func (f fieldsFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(f))
+}
+
+func (f fieldsFlag) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[strings.TrimSpace(kv[0])] = kv[1]
+	return nil
+}
+
+func main() {
+	fields := fieldsFlag{}
+	configPath := flag.String("config", "", "YAML config file of the hook, the BURZUM_ environment variables are used if empty")
+	envPrefix := flag.String("env-prefix", "BURZUM", "prefix of the environment variables of the hook config")
+	token := flag.String("token", "", "Burzum token, overrides the config one")
+	levelName := flag.String("level", "info", "level of the lines without one")
+	checkpointPath := flag.String("checkpoint", "", "file storing the positions of the tailed files, disabled if empty")
+	checkpointInterval := flag.Duration("checkpoint-interval", 5*time.Second, "interval between checkpoints")
+	poll := flag.Duration("poll", 250*time.Millisecond, "interval between checks for new lines")
+	fromEnd := flag.Bool("from-end", false, "skip the existing content of files without a checkpoint")
+	flushTimeout := flag.Duration("flush-timeout", 10*time.Second, "how long to wait for the queued lines to be sent")
+	flag.Var(fields, "field", "static `key=value` field added to every line, repeatable")
+	flag.Parse()
+
+	level, err := logrus.ParseLevel(*levelName)
+	if err != nil {
+		log.Fatalf("burzum-ship: %v", err)
+	}
+	config, err := loadConfig(*configPath, *envPrefix, *token, fields)
+	if err != nil {
+		log.Fatalf("burzum-ship: %v", err)
+	}
+
+	hook, err := logzum.NewWithConfig("", config)
+	if hook == nil {
+		// the sink could not be started
+		log.Fatalf("burzum-ship: %v", err)
+	}
+	if err != nil {
+		// the hook connects again on the first line
+		log.Printf("burzum-ship: %v", err)
+	}
+	done := make(chan struct{})
+	ship := shipper(hook, window(config), level, config.Formatter, done)
+
+	files := flag.Args()
+	if len(files) == 0 || (len(files) == 1 && files[0] == "-") {
+		if err := readAll(os.Stdin, ship); err != nil {
+			log.Printf("burzum-ship: unable to read stdin: %v", err)
+		}
+		shutdown(hook, *flushTimeout)
+		return
+	}
+
+	checkpoints, err := loadCheckpoints(*checkpointPath)
+	if err != nil {
+		log.Fatalf("burzum-ship: unable to load checkpoints: %v", err)
+	}
+	var wg sync.WaitGroup
+	for _, path := range files {
+		t := &tailer{path: path, poll: *poll, fromEnd: *fromEnd, checkpoints: checkpoints, ship: ship}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.run(done)
+		}()
+	}
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGTERM)
+	cp := &checkpointer{hook: hook, checkpoints: checkpoints, timeout: *flushTimeout}
+	ticker := time.NewTicker(*checkpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cp.checkpoint()
+		case <-sigC:
+			close(done)
+			wg.Wait()
+			cp.checkpoint()
+			shutdown(hook, *flushTimeout)
+			return
+		}
+	}
+}
+
+// loadConfig reads the hook config from the file at path, or from the environment, with the token and fields
+// given as flags.
+func loadConfig(path, envPrefix, token string, fields map[string]interface{}) (logzum.Config, error) {
+	var config logzum.Config
+	var err error
+	if path != "" {
+		config, err = logzum.LoadConfig(path)
+	} else {
+		config, err = logzum.ConfigFromEnv(envPrefix)
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if token != "" {
+		config.Token = token
+	}
+	if config.Token == "" {
+		return config, fmt.Errorf("a token is required")
+	}
+	if len(fields) > 0 {
+		merged := make(map[string]interface{}, len(config.Fields)+len(fields))
+		for k, v := range config.Fields {
+			merged[k] = v
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+		config.Fields = merged
+	}
+	return config, nil
+}
+
+// window returns the number of lines the hook may hold queued before shipping waits, half of the queue of its
+// transport or sink, so the chunks of the lines split by MaxEntryBytes fit in it too.
+func window(config logzum.Config) uint64 {
+	size := config.Buffersize
+	if size == 0 {
+		size = logzum.DefaultConfig.Buffersize
+	}
+	sinkQueue := func(queueSize int) {
+		size = queueSize
+		if size == 0 {
+			size = 2048
+		}
+	}
+	switch {
+	case config.OTLP != nil:
+		sinkQueue(config.OTLP.QueueSize)
+	case config.Loki != nil:
+		sinkQueue(config.Loki.QueueSize)
+	case config.Elasticsearch != nil:
+		sinkQueue(config.Elasticsearch.QueueSize)
+	case config.Fluentd != nil:
+		sinkQueue(config.Fluentd.QueueSize)
+	}
+	if size < 2 {
+		return 1
+	}
+	return uint64(size / 2)
+}
+
+// shipper returns the function firing the lines to hook. It waits while window lines are queued in the hook, so
+// they are not dropped when Burzum is slower than the files grow, and returns false if done is closed meanwhile.
+func shipper(hook logzum.Hook, window uint64, level logrus.Level, formatter logrus.Formatter, done <-chan struct{}) func(line []byte) bool {
+	logger := &logrus.Logger{Out: ioutil.Discard, Formatter: formatter, Hooks: make(logrus.LevelHooks), Level: logrus.DebugLevel}
+	return func(line []byte) bool {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return true
+		}
+		for hook.Stats().Queued >= window {
+			select {
+			case <-done:
+				return false
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		entry := parseLine(line, level, time.Now())
+		entry.Logger = logger
+		if err := hook.Fire(entry); err != nil {
+			log.Printf("burzum-ship: %v", err)
+		}
+		return true
+	}
+}
+
+// checkpointer saves the positions of the lines fired so far, once they left the hook queue. The positions are
+// not saved anymore once lines were dropped or failed, so they are shipped again from the last checkpoint on
+// restart.
+type checkpointer struct {
+	hook logzum.Hook
+
+	checkpoints *checkpoints
+
+	timeout time.Duration
+
+	// lost is the number of lines dropped or failed when it was last logged
+	lost uint64
+}
+
+func (c *checkpointer) checkpoint() {
+	positions := c.checkpoints.snapshot()
+	if err := c.hook.Flush(c.timeout); err != nil {
+		log.Printf("burzum-ship: checkpoint skipped: %v", err)
+		return
+	}
+	stats := c.hook.Stats()
+	if lost := stats.Dropped + stats.Failed; lost > 0 {
+		if lost != c.lost {
+			log.Printf("burzum-ship: checkpoint skipped: %d lines were not sent, they are shipped again on restart", lost)
+			c.lost = lost
+		}
+		return
+	}
+	if err := c.checkpoints.save(positions); err != nil {
+		log.Printf("burzum-ship: unable to save checkpoints: %v", err)
+	}
+}
+
+func shutdown(hook logzum.Hook, timeout time.Duration) {
+	if err := hook.Flush(timeout); err != nil {
+		log.Printf("burzum-ship: %v, %d lines not sent", err, hook.Stats().Queued)
+	}
+	hook.Close()
+	if failed := hook.Stats().Failed; failed > 0 {
+		log.Printf("burzum-ship: %d lines failed to be sent", failed)
+	}
+}
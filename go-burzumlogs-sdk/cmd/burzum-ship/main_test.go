@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum/logzumtest"
+)
+
+func TestLoadConfig(t *testing.T) {
+	os.Setenv("SHIPTEST_TOKEN", "env-token")
+	os.Setenv("SHIPTEST_FIELDS", "app=batch,env=dev")
+	defer os.Unsetenv("SHIPTEST_TOKEN")
+	defer os.Unsetenv("SHIPTEST_FIELDS")
+
+	config, err := loadConfig("", "SHIPTEST", "", map[string]interface{}{"env": "prod", "job": "nightly"})
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", config.Token)
+	assert.Equal(t, map[string]interface{}{"app": "batch", "env": "prod", "job": "nightly"}, config.Fields)
+
+	config, err = loadConfig("", "SHIPTEST", "flag-token", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "flag-token", config.Token)
+
+	_, err = loadConfig("", "SHIPTEST_NONE", "", nil)
+	assert.EqualError(t, err, "a token is required")
+
+	_, err = loadConfig("missing.yaml", "", "token", nil)
+	assert.Error(t, err)
+}
+
+func TestShip(t *testing.T) {
+	s := logzumtest.NewServer()
+	defer s.Close()
+
+	config := s.Config()
+	config.Fields = map[string]interface{}{"app": "batch"}
+	hook, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+
+	ship := func(line []byte) bool {
+		e := parseLine(line, logrus.InfoLevel, time.Now())
+		require.NoError(t, hook.Fire(e))
+		return true
+	}
+	require.NoError(t, readAll(strings.NewReader(`{"message":"json","level":"error","rows":3}`+"\nplain"), ship))
+	shutdown(hook, time.Second)
+
+	entries := s.WaitForEntries(t, 2, time.Second)
+	s.AssertToken(t, "foo")
+	assert.Equal(t, "json", entries[0].Message())
+	assert.Equal(t, "error", entries[0].Level())
+	assert.Equal(t, "3", entries[0].Field("rows"))
+	assert.Equal(t, "batch", entries[0].Field("app"))
+	assert.Equal(t, "plain", entries[1].Message())
+}
+
+// queueHook counts the lines fired as queued, until the test sends them.
+type queueHook struct {
+	logzum.Hook
+
+	queued uint64
+
+	dropped uint64
+}
+
+func (h *queueHook) Fire(entry *logrus.Entry) error {
+	atomic.AddUint64(&h.queued, 1)
+	return nil
+}
+
+func (h *queueHook) Flush(timeout time.Duration) error {
+	return nil
+}
+
+func (h *queueHook) Stats() logzum.Stats {
+	return logzum.Stats{Queued: atomic.LoadUint64(&h.queued), Dropped: atomic.LoadUint64(&h.dropped)}
+}
+
+func TestWindow(t *testing.T) {
+	config := logzum.DefaultConfig
+	assert.Equal(t, uint64(500), window(config))
+	config.Buffersize = 0
+	assert.Equal(t, uint64(500), window(config), "the default buffer size")
+	config.Buffersize = 1
+	assert.Equal(t, uint64(1), window(config))
+	config.Loki = &logzum.LokiConfig{}
+	assert.Equal(t, uint64(1024), window(config), "the default queue of the sinks")
+	config.Loki.QueueSize = 100
+	assert.Equal(t, uint64(50), window(config))
+}
+
+func TestShipperBackpressure(t *testing.T) {
+	hook := &queueHook{}
+	done := make(chan struct{})
+	ship := shipper(hook, 2, logrus.InfoLevel, &logrus.JSONFormatter{}, done)
+
+	assert.True(t, ship([]byte("first\n")))
+	assert.True(t, ship([]byte("second\n")))
+	assert.True(t, ship([]byte("\n")), "empty lines are skipped without waiting")
+
+	shipped := make(chan bool)
+	go func() { shipped <- ship([]byte("third\n")) }()
+	select {
+	case <-shipped:
+		t.Fatal("the line was shipped while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	atomic.StoreUint64(&hook.queued, 1)
+	assert.True(t, <-shipped, "the line is shipped once the queue has room")
+
+	go func() { shipped <- ship([]byte("fourth\n")) }()
+	close(done)
+	assert.False(t, <-shipped, "the line is not shipped once done is closed")
+	assert.Equal(t, uint64(2), hook.Stats().Queued)
+}
+
+func TestCheckpointerAfterLostLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burzum-ship")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoints.json")
+
+	cps, err := loadCheckpoints(path)
+	require.NoError(t, err)
+	hook := &queueHook{}
+	cp := &checkpointer{hook: hook, checkpoints: cps, timeout: time.Second}
+
+	cps.set("a.log", position{ID: 1, Offset: 10})
+	cp.checkpoint()
+	saved, err := loadCheckpoints(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]position{"a.log": {ID: 1, Offset: 10}}, saved.snapshot())
+
+	atomic.StoreUint64(&hook.dropped, 1)
+	cps.set("a.log", position{ID: 1, Offset: 20})
+	cp.checkpoint()
+	saved, err = loadCheckpoints(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]position{"a.log": {ID: 1, Offset: 10}}, saved.snapshot(), "the lost lines are shipped again on restart")
+}
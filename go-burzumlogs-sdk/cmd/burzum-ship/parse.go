@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// messageKeys, levelKeys and timeKeys are the fields read into the entry message, level and time, in order of
+	// preference, the other fields of a JSON line are kept as they are.
+	messageKeys = []string{"message", "msg"}
+	levelKeys   = []string{"level", "severity"}
+	timeKeys    = []string{"time", "timestamp", "ts"}
+)
+
+// parseLine converts a JSON object or a plain text line into an entry, logged at defaultLevel unless the line has
+// a level.
+func parseLine(line []byte, defaultLevel logrus.Level, now time.Time) *logrus.Entry {
+	line = bytes.TrimRight(line, "\r\n")
+	entry := &logrus.Entry{
+		Data:    logrus.Fields{},
+		Time:    now,
+		Level:   defaultLevel,
+		Message: string(line),
+	}
+
+	var fields map[string]interface{}
+	if json.Unmarshal(line, &fields) != nil || fields == nil {
+		return entry
+	}
+
+	entry.Message = ""
+	if s, ok := take(fields, messageKeys); ok {
+		entry.Message = s
+	}
+	if s, ok := take(fields, levelKeys); ok {
+		if level, err := logrus.ParseLevel(s); err == nil {
+			entry.Level = level
+		} else {
+			// kept as a field, not to lose it
+			fields["level.original"] = s
+		}
+	}
+	if s, ok := take(fields, timeKeys); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			entry.Time = t
+		} else {
+			fields["time.original"] = s
+		}
+	}
+	for k, v := range fields {
+		entry.Data[k] = v
+	}
+	return entry
+}
+
+// take removes the first of keys holding a string from fields.
+func take(fields map[string]interface{}, keys []string) (string, bool) {
+	for _, k := range keys {
+		if s, ok := fields[k].(string); ok {
+			delete(fields, k)
+			return s, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Now()
+
+	e := parseLine([]byte(`{"msg":"done","level":"warning","time":"2019-10-01T10:00:00.5Z","rows":3}`+"\n"), logrus.InfoLevel, now)
+	assert.Equal(t, "done", e.Message)
+	assert.Equal(t, logrus.WarnLevel, e.Level)
+	assert.Equal(t, time.Date(2019, 10, 1, 10, 0, 0, 5e8, time.UTC), e.Time.UTC())
+	assert.Equal(t, logrus.Fields{"rows": float64(3)}, e.Data)
+
+	e = parseLine([]byte(`{"message":"odd","severity":"loud","ts":1569924000}`), logrus.InfoLevel, now)
+	assert.Equal(t, "odd", e.Message)
+	assert.Equal(t, logrus.InfoLevel, e.Level)
+	assert.Equal(t, now, e.Time)
+	assert.Equal(t, logrus.Fields{"level.original": "loud", "ts": float64(1569924000)}, e.Data, "a numeric time is kept as a field")
+
+	e = parseLine([]byte("plain text\r\n"), logrus.ErrorLevel, now)
+	assert.Equal(t, "plain text", e.Message)
+	assert.Equal(t, logrus.ErrorLevel, e.Level)
+	assert.Empty(t, e.Data)
+
+	e = parseLine([]byte(`["not", "an", "object"]`), logrus.InfoLevel, now)
+	assert.Equal(t, `["not", "an", "object"]`, e.Message)
+}
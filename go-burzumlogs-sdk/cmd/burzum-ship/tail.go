@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// tailer ships the lines appended to a file, from its checkpointed position, reopening the file when it is
+// rotated and reading it again from the start when it is truncated.
+type tailer struct {
+	path string
+
+	poll time.Duration
+
+	// fromEnd skips the existing content of files without a checkpoint
+	fromEnd bool
+
+	checkpoints *checkpoints
+
+	// ship returns false if the line could not be shipped, the tailer stops then
+	ship func(line []byte) bool
+
+	file *os.File
+
+	info os.FileInfo
+
+	reader *bufio.Reader
+
+	// offset is the end of the last shipped line
+	offset int64
+
+	// pending holds a line not terminated yet
+	pending []byte
+}
+
+// run tails the file until done is closed.
+func (t *tailer) run(done <-chan struct{}) {
+	defer func() {
+		if t.file != nil {
+			t.file.Close()
+		}
+	}()
+
+	resume, missing := true, false
+	for {
+		if t.file == nil {
+			err := t.open(resume)
+			if err != nil && !missing {
+				log.Printf("burzum-ship: waiting for %s: %v", t.path, err)
+			}
+			missing = err != nil
+			if err == nil {
+				// the next files opened are new, after a rotation
+				resume = false
+			}
+		}
+		if t.file != nil && !(t.read() && t.check()) {
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(t.poll):
+		}
+	}
+}
+
+// open opens the file at the checkpointed position if resume, at the start otherwise.
+func (t *tailer) open(resume bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	offset := int64(0)
+	if resume {
+		if p, ok := t.checkpoints.get(t.path); ok {
+			if p.ID == fileID(info) && p.Offset <= info.Size() {
+				offset = p.Offset
+			}
+		} else if t.fromEnd {
+			offset = info.Size()
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	t.file, t.info, t.offset, t.pending = f, info, offset, nil
+	t.reader = bufio.NewReader(f)
+	t.checkpoints.set(t.path, position{ID: fileID(info), Offset: offset})
+	return nil
+}
+
+// read ships the complete lines available, it returns false if a line could not be shipped.
+func (t *tailer) read() bool {
+	for {
+		line, err := t.reader.ReadBytes('\n')
+		if err != nil {
+			t.pending = append(t.pending, line...)
+			if err != io.EOF {
+				log.Printf("burzum-ship: unable to read %s: %v", t.path, err)
+			}
+			return true
+		}
+		if len(t.pending) > 0 {
+			line = append(t.pending, line...)
+			t.pending = nil
+		}
+		if !t.ship(line) {
+			return false
+		}
+		t.offset += int64(len(line))
+		t.checkpoints.set(t.path, position{ID: fileID(t.info), Offset: t.offset})
+	}
+}
+
+// check closes the file if it was rotated, so the new one is opened, and rewinds it if it was truncated. It
+// returns false if a line could not be shipped.
+func (t *tailer) check() bool {
+	info, err := os.Stat(t.path)
+	if err != nil || !os.SameFile(info, t.info) {
+		// the lines written before the rotation
+		if !t.read() {
+			return false
+		}
+		// the rotated file won't be terminated, its last line is shipped as it is
+		if len(t.pending) > 0 {
+			if !t.ship(t.pending) {
+				return false
+			}
+			t.offset += int64(len(t.pending))
+			t.pending = nil
+		}
+		t.file.Close()
+		t.file = nil
+		return true
+	}
+	if info.Size() < t.offset+int64(len(t.pending)) {
+		log.Printf("burzum-ship: %s was truncated, reading it from the start", t.path)
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			t.file.Close()
+			t.file = nil
+			return true
+		}
+		t.offset, t.pending = 0, nil
+		t.reader.Reset(t.file)
+		t.checkpoints.set(t.path, position{ID: fileID(info), Offset: 0})
+	}
+	return true
+}
+
+// readAll ships the lines of r until its end, as for stdin, or until a line could not be shipped.
+func readAll(r io.Reader, ship func(line []byte) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 && !ship(line) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
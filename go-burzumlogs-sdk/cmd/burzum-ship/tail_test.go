@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *collector) ship(line []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, string(line))
+	return true
+}
+
+func (c *collector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func (c *collector) waitFor(t *testing.T, want ...string) {
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, c.get()) }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, want, c.get())
+}
+
+func appendFile(t *testing.T, path, data string) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func startTailer(t *testing.T, path string, cps *checkpoints, c *collector) func() {
+	tl := &tailer{path: path, poll: 5 * time.Millisecond, checkpoints: cps, ship: c.ship}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		tl.run(done)
+		close(stopped)
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func TestTailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burzum-ship")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	cps, err := loadCheckpoints(filepath.Join(dir, "checkpoints.json"))
+	require.NoError(t, err)
+	c := &collector{}
+	stop := startTailer(t, path, cps, c)
+
+	// the file doesn't exist yet
+	appendFile(t, path, "one\ntw")
+	c.waitFor(t, "one\n")
+	appendFile(t, path, "o\n")
+	c.waitFor(t, "one\n", "two\n")
+
+	// rotation, the unterminated last line is shipped too
+	appendFile(t, path, "three")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "four\n")
+	c.waitFor(t, "one\n", "two\n", "three", "four\n")
+
+	// truncation, detected as the file is shorter than the shipped lines
+	require.NoError(t, ioutil.WriteFile(path, []byte("5\n"), 0644))
+	c.waitFor(t, "one\n", "two\n", "three", "four\n", "5\n")
+
+	stop()
+	require.NoError(t, cps.save(cps.snapshot()))
+
+	// a restart resumes from the checkpoint
+	appendFile(t, path, "six\n")
+	cps, err = loadCheckpoints(filepath.Join(dir, "checkpoints.json"))
+	require.NoError(t, err)
+	p, ok := cps.get(path)
+	require.True(t, ok)
+	assert.Equal(t, int64(len("5\n")), p.Offset)
+
+	c = &collector{}
+	stop = startTailer(t, path, cps, c)
+	c.waitFor(t, "six\n")
+	stop()
+
+	// a rotation while stopped is detected by the file ID
+	require.NoError(t, cps.save(cps.snapshot()))
+	require.NoError(t, os.Rename(path, path+".2"))
+	appendFile(t, path, "seven\n")
+	cps, err = loadCheckpoints(filepath.Join(dir, "checkpoints.json"))
+	require.NoError(t, err)
+	c = &collector{}
+	stop = startTailer(t, path, cps, c)
+	c.waitFor(t, "seven\n")
+	stop()
+}
+
+func TestTailerFromEnd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burzum-ship")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "old\n")
+
+	cps, err := loadCheckpoints("")
+	require.NoError(t, err)
+	c := &collector{}
+	tl := &tailer{path: path, poll: 5 * time.Millisecond, fromEnd: true, checkpoints: cps, ship: c.ship}
+	done := make(chan struct{})
+	go tl.run(done)
+	defer close(done)
+
+	assert.Eventually(t, func() bool { _, ok := cps.get(path); return ok }, time.Second, 5*time.Millisecond)
+	appendFile(t, path, "new\n")
+	c.waitFor(t, "new\n")
+}
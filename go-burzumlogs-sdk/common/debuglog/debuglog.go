@@ -0,0 +1,113 @@
+package debuglog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// Header is the HTTP header name carrying the signed log level.
+	Header = "X-Debug-Log"
+
+	// MetadataKey is the gRPC metadata key carrying the signed log level.
+	MetadataKey = "x-debug-log"
+)
+
+type ctxMarker struct{}
+
+var (
+	ctxMarkerKey = &ctxMarker{}
+)
+
+// Sign returns the header value that lowers the log level to level until expires.
+//
+// The value is `<level>.<expires unix>.<hex HMAC-SHA256 of level and expires>`.
+func Sign(secret []byte, level logrus.Level, expires time.Time) string {
+	payload := level.String() + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + hex.EncodeToString(mac(secret, payload))
+}
+
+// Verify returns the log level of a header value signed with secret, it fails if the value is
+// malformed, the signature doesn't match or it is expired at now.
+func Verify(secret []byte, value string, now time.Time) (logrus.Level, bool) {
+	if len(secret) == 0 || value == "" {
+		return 0, false
+	}
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return 0, false
+	}
+	payload, signature := value[:i], value[i+1:]
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, mac(secret, payload)) {
+		return 0, false
+	}
+	parts := strings.SplitN(payload, ".", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return 0, false
+	}
+	level, err := logrus.ParseLevel(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+func mac(secret []byte, payload string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// Extract takes the call-scoped signed value from context.
+func Extract(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(ctxMarkerKey).(string)
+	return value, ok
+}
+
+// Inject the signed value, so it is propagated to downstream calls.
+func Inject(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, ctxMarkerKey, value)
+}
+
+// RoundTripper returns a http.RoundTripper that propagates the signed value in the request context
+// to downstream services. If next is nil, http.DefaultTransport is used.
+func RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		value, ok := Extract(req.Context())
+		if !ok {
+			return next.RoundTrip(req)
+		}
+		// RoundTrippers must not modify the request.
+		r := new(http.Request)
+		*r = *req
+		r.Header = make(http.Header, len(req.Header)+1)
+		for k, v := range req.Header {
+			r.Header[k] = v
+		}
+		r.Header.Set(Header, value)
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
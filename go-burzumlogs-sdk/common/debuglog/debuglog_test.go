@@ -0,0 +1,67 @@
+package debuglog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
+)
+
+var (
+	secret = []byte("secret")
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	value := debuglog.Sign(secret, logrus.DebugLevel, now.Add(10*time.Minute))
+
+	level, ok := debuglog.Verify(secret, value, now)
+	assert.True(t, ok)
+	assert.Equal(t, logrus.DebugLevel, level)
+
+	_, ok = debuglog.Verify([]byte("other"), value, now)
+	assert.False(t, ok, "a value signed with another secret must be refused")
+
+	_, ok = debuglog.Verify(secret, value, now.Add(11*time.Minute))
+	assert.False(t, ok, "an expired value must be refused")
+
+	_, ok = debuglog.Verify(secret, "info"+value[len("debug"):], now)
+	assert.False(t, ok, "a tampered value must be refused")
+
+	_, ok = debuglog.Verify(nil, value, now)
+	assert.False(t, ok, "values must be refused without a secret")
+
+	for _, v := range []string{"", "debug", "debug.1", "debug.x.00"} {
+		_, ok = debuglog.Verify(secret, v, now)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestRoundTripper(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(debuglog.Header)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: debuglog.RoundTripper(nil)}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req.WithContext(debuglog.Inject(context.Background(), "value")))
+	require.NoError(t, err)
+	assert.Equal(t, "value", received, "the value in context must be propagated")
+	assert.Empty(t, req.Header.Get(debuglog.Header), "the original request must not be modified")
+
+	_, err = client.Do(req)
+	require.NoError(t, err)
+	assert.Empty(t, received)
+}
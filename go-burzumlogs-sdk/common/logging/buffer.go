@@ -0,0 +1,183 @@
+package bzlogging
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// DefaultBufferSize is the per-request memory cap used when none is given to NewBuffer.
+	DefaultBufferSize = 1 << 20 // 1 MB
+
+	// fieldSize is the estimated size of non string field values.
+	fieldSize = 16
+)
+
+// Buffer holds the request entries below a level until the request outcome is known.
+//
+// Entries at or above the level are logged right away, the buffered ones are either flushed at their
+// own levels, or discarded.
+type Buffer struct {
+	mu sync.Mutex
+
+	target *logrus.Entry
+
+	entry *logrus.Entry
+
+	level logrus.Level
+
+	maxBytes int
+
+	size int
+
+	entries []*logrus.Entry
+
+	dropped int
+
+	closed bool
+}
+
+// NewBuffer creates a Buffer for target, holding entries below level up to maxBytes.
+func NewBuffer(target *logrus.Entry, level logrus.Level, maxBytes int) *Buffer {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBufferSize
+	}
+	b := &Buffer{
+		target:   target,
+		level:    level,
+		maxBytes: maxBytes,
+	}
+
+	logger := &logrus.Logger{
+		Out:       ioutil.Discard,
+		Formatter: nopFormatter{},
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.TraceLevel,
+	}
+	logger.Hooks.Add(&bufferHook{b})
+	b.entry = logrus.NewEntry(logger).WithFields(target.Data)
+
+	return b
+}
+
+// Entry returns the entry that must be used to log during the request.
+func (b *Buffer) Entry() *logrus.Entry {
+	return b.entry
+}
+
+// Flush writes the buffered entries and stops buffering, it returns how many entries were dropped
+// for exceeding the memory cap.
+//
+// The entries keep their level, they are logged through the target logger lowered to it with WithLevel,
+// so they reach its output and hooks whatever their levels.
+func (b *Buffer) Flush() int {
+	entries, dropped := b.close()
+
+	for _, e := range entries {
+		WithLevel(b.target, e.Level).WithFields(e.Data).WithTime(e.Time).Log(e.Level, e.Message)
+	}
+
+	return dropped
+}
+
+// Discard drops the buffered entries and stops buffering.
+func (b *Buffer) Discard() {
+	b.close()
+}
+
+func (b *Buffer) close() ([]*logrus.Entry, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries, dropped := b.entries, b.dropped
+	b.entries = nil
+	b.size = 0
+	b.closed = true
+	return entries, dropped
+}
+
+func (b *Buffer) add(entry *logrus.Entry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	b.entries = append(b.entries, &logrus.Entry{
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	})
+	b.size += entrySize(entry)
+
+	// the oldest entries are dropped first, keeping the trail closest to the failure.
+	for b.size > b.maxBytes && len(b.entries) > 0 {
+		b.size -= entrySize(b.entries[0])
+		b.entries[0] = nil
+		b.entries = b.entries[1:]
+		b.dropped++
+	}
+	return true
+}
+
+func entrySize(entry *logrus.Entry) int {
+	size := len(entry.Message)
+	for k, v := range entry.Data {
+		size += len(k)
+		if s, ok := v.(string); ok {
+			size += len(s)
+		} else {
+			size += fieldSize
+		}
+	}
+	return size
+}
+
+// bufferHook receives every entry logged through the Buffer entry.
+type bufferHook struct {
+	buffer *Buffer
+}
+
+func (h *bufferHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *bufferHook) Fire(entry *logrus.Entry) error {
+	if entry.Level > h.buffer.level && h.buffer.add(entry) {
+		return nil
+	}
+	forward(h.buffer.target.Logger.WithFields(entry.Data), entry.Level, entry.Message)
+	return nil
+}
+
+func forward(entry *logrus.Entry, level logrus.Level, msg string) {
+	switch level {
+	case logrus.TraceLevel:
+		entry.Trace(msg)
+	case logrus.DebugLevel:
+		entry.Debug(msg)
+	case logrus.InfoLevel:
+		entry.Info(msg)
+	case logrus.WarnLevel:
+		entry.Warning(msg)
+	case logrus.ErrorLevel:
+		entry.Error(msg)
+	case logrus.FatalLevel:
+		entry.Fatal(msg)
+	case logrus.PanicLevel:
+		entry.Panic(msg)
+	}
+}
+
+// nopFormatter avoids formatting the entries of the Buffer logger, which are never written.
+type nopFormatter struct{}
+
+func (nopFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
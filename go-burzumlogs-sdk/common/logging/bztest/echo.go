@@ -0,0 +1,47 @@
+package bztest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/labstack/echo"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/requestid"
+)
+
+// EchoHarness runs echo handlers through the SDK middleware chain, request ID, logging and recover, recording the
+// entries they log.
+type EchoHarness struct {
+	*Recorder
+
+	Echo *echo.Echo
+
+	middlewares []echo.MiddlewareFunc
+}
+
+// NewEchoHarness creates an EchoHarness logging to a new Recorder.
+func NewEchoHarness(opts ...Option) *EchoHarness {
+	o := evaluateOpt(opts)
+	r := NewRecorder()
+	return &EchoHarness{
+		Recorder: r,
+		Echo:     echo.New(),
+		middlewares: []echo.MiddlewareFunc{
+			echo_requestid.RequestID(),
+			echo_logzum.Logger(r.Entry(), o.echoOpts...),
+			echo_logzum.Recover(o.echoOpts...),
+		},
+	}
+}
+
+// Serve runs handler for req through the middleware chain and returns the response.
+func (h *EchoHarness) Serve(req *http.Request, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+	rec := httptest.NewRecorder()
+	// the logging middleware already handled the error
+	handler(h.Echo.NewContext(req, rec))
+	return rec
+}
@@ -0,0 +1,88 @@
+package bztest
+
+import (
+	"net"
+
+	"golang.org/x/net/context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/requestid"
+)
+
+// GRPCHarness runs gRPC methods through the SDK interceptor chain, request ID, logging and recovery, recording
+// the entries they log.
+//
+// The services registered on Server are reached through Conn, which sends the request ID of the call context as
+// the request ID client interceptor does.
+type GRPCHarness struct {
+	*Recorder
+
+	Server *grpc.Server
+
+	Conn *grpc.ClientConn
+
+	unary grpc.UnaryServerInterceptor
+
+	listener *bufconn.Listener
+}
+
+// NewGRPCHarness starts an in-memory gRPC server with the services added by register.
+func NewGRPCHarness(register func(s *grpc.Server), opts ...Option) (*GRPCHarness, error) {
+	o := evaluateOpt(opts)
+	r := NewRecorder()
+
+	unary := grpc_middleware.ChainUnaryServer(
+		grpc_requestid.UnaryServerInterceptor(),
+		grpc_logzum.UnaryServerInterceptor(r.Entry(), o.grpcOpts...),
+		grpc_logzum.UnaryServerRecoveryInterceptor(o.grpcOpts...),
+	)
+	stream := grpc_middleware.ChainStreamServer(
+		grpc_requestid.StreamServerInterceptor(),
+		grpc_logzum.StreamServerInterceptor(r.Entry(), o.grpcOpts...),
+		grpc_logzum.StreamServerRecoveryInterceptor(o.grpcOpts...),
+	)
+	s := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	if register != nil {
+		register(s)
+	}
+
+	l := bufconn.Listen(1 << 20)
+	go s.Serve(l)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.Dial()
+		}),
+		grpc.WithUnaryInterceptor(grpc_requestid.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(grpc_requestid.StreamClientInterceptor()),
+	)
+	if err != nil {
+		s.Stop()
+		return nil, err
+	}
+
+	return &GRPCHarness{
+		Recorder: r,
+		Server:   s,
+		Conn:     conn,
+		unary:    unary,
+		listener: l,
+	}, nil
+}
+
+// Unary runs handler as the unary method fullMethod through the interceptor chain, without a connection.
+func (h *GRPCHarness) Unary(ctx context.Context, fullMethod string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	return h.unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+}
+
+// Close closes the connection and stops the server.
+func (h *GRPCHarness) Close() {
+	h.Conn.Close()
+	h.Server.Stop()
+	h.listener.Close()
+}
@@ -0,0 +1,112 @@
+package bztest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging/bztest"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/logging/logrus"
+)
+
+func TestEchoHarness(t *testing.T) {
+	h := bztest.NewEchoHarness(bztest.WithEchoOptions(echo_logzum.WithSkipper(func(c echo.Context) bool {
+		return c.Request().URL.Path == "/health"
+	})))
+
+	req := httptest.NewRequest(echo.GET, "/orders", nil)
+	req.Header.Set("X-Request-ID", "foo")
+	rec := h.Serve(req, func(c echo.Context) error {
+		bzlogging.Extract(c.Request().Context()).Info("loading")
+		return c.String(http.StatusNotFound, "not found")
+	})
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "foo", rec.Header().Get("X-Request-ID"))
+	h.AssertAllHaveField(t, "requestID", "foo")
+	h.AssertAllHaveField(t, "http.uri", "/orders")
+	h.AssertCompletionLine(t, logrus.WarnLevel, http.StatusNotFound)
+	assert.Len(t, h.EntriesForRequest("foo"), 2)
+
+	h.Reset()
+	h.Serve(httptest.NewRequest(echo.GET, "/health", nil), func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	assert.Empty(t, h.Entries(), "skipped by the custom option")
+}
+
+func TestEchoHarnessPanic(t *testing.T) {
+	h := bztest.NewEchoHarness()
+
+	rec := h.Serve(httptest.NewRequest(echo.GET, "/", nil), func(c echo.Context) error {
+		panic("boom")
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	h.AssertCompletionLine(t, logrus.ErrorLevel, http.StatusInternalServerError)
+	id := rec.Header().Get("X-Request-ID")
+	require.NotEmpty(t, id)
+	assert.Len(t, h.EntriesForRequest(id), 2, "the panic and the completion line")
+}
+
+type pingService struct {
+	pb_testproto.TestServiceServer
+}
+
+func (s *pingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	bzlogging.Extract(ctx).Info("some ping")
+	return s.TestServiceServer.Ping(ctx, ping)
+}
+
+func TestGRPCHarness(t *testing.T) {
+	h, err := bztest.NewGRPCHarness(func(s *grpc.Server) {
+		pb_testproto.RegisterTestServiceServer(s, &pingService{&grpc_testing.TestPingService{T: t}})
+	})
+	require.NoError(t, err)
+	defer h.Close()
+
+	client := pb_testproto.NewTestServiceClient(h.Conn)
+	ctx := requestid.Inject(context.Background(), "foo")
+	_, err = client.Ping(ctx, &pb_testproto.PingRequest{Value: "something"})
+	require.NoError(t, err)
+
+	h.AssertAllHaveField(t, "requestID", "foo")
+	h.AssertAllHaveField(t, "grpc.method", "Ping")
+	h.AssertCompletionLine(t, logrus.InfoLevel, codes.OK)
+
+	h.Reset()
+	_, err = client.PingError(ctx, &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.NotFound)})
+	require.Error(t, err)
+	h.AssertCompletionLine(t, logrus.InfoLevel, "NotFound")
+}
+
+func TestGRPCHarnessUnary(t *testing.T) {
+	h, err := bztest.NewGRPCHarness(nil)
+	require.NoError(t, err)
+	defer h.Close()
+
+	ctx := requestid.Inject(context.Background(), "bar")
+	_, err = h.Unary(ctx, "/orders.Orders/Get", nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		bzlogging.Extract(ctx).Debug("loading")
+		panic("boom")
+	})
+
+	assert.Equal(t, codes.Internal, grpc.Code(err))
+	h.AssertAllHaveField(t, "requestID", "bar")
+	h.AssertCompletionLine(t, logrus.ErrorLevel, codes.Internal)
+	assert.Len(t, h.EntriesForRequest("bar"), 3)
+}
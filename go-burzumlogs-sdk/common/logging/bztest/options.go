@@ -0,0 +1,46 @@
+package bztest
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
+)
+
+type options struct {
+	echoOpts []echo_logzum.Option
+	grpcOpts []grpc_logzum.Option
+}
+
+func evaluateOpt(opts []Option) *options {
+	o := &options{
+		echoOpts: []echo_logzum.Option{echo_logzum.WithRequestId(requestIDField)},
+		grpcOpts: []grpc_logzum.Option{grpc_logzum.WithRequestId(requestIDField)},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type Option func(*options)
+
+// WithEchoOptions customizes the options of the echo logging middlewares, the request ID is logged by default.
+func WithEchoOptions(opts ...echo_logzum.Option) Option {
+	return func(o *options) {
+		o.echoOpts = append(o.echoOpts, opts...)
+	}
+}
+
+// WithGRPCOptions customizes the options of the gRPC logging interceptors, the request ID is logged by default.
+func WithGRPCOptions(opts ...grpc_logzum.Option) Option {
+	return func(o *options) {
+		o.grpcOpts = append(o.grpcOpts, opts...)
+	}
+}
+
+func requestIDField(ctx context.Context) (string, interface{}) {
+	return bzlogging.RequestIDTorequestIDField(requestid.Extract(ctx))
+}
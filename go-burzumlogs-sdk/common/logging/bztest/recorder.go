@@ -0,0 +1,130 @@
+// Package bztest provides a recording logger with assertions for the entries of a request, and harnesses
+// running echo handlers and gRPC methods through the SDK middleware chain.
+package bztest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+var (
+	// CompletionMessages are the messages of the lines logged by the logging middlewares when a request ends.
+	CompletionMessages = []string{"finished http call", "finished unary call", "finished streaming call"}
+
+	// HTTPStatusField and GRPCCodeField hold the status of the completion lines.
+	HTTPStatusField = "http.status"
+	GRPCCodeField   = "grpc.code"
+)
+
+// Recorder is a logger recording every entry, at any level, without writing them.
+type Recorder struct {
+	Logger *logrus.Logger
+
+	hook *test.Hook
+}
+
+// NewRecorder creates a Recorder logging at debug level.
+func NewRecorder() *Recorder {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.Level = logrus.DebugLevel
+	return &Recorder{Logger: logger, hook: test.NewLocal(logger)}
+}
+
+// Entry returns an entry of the recording logger, to be given to the logging middlewares.
+func (r *Recorder) Entry() *logrus.Entry {
+	return logrus.NewEntry(r.Logger)
+}
+
+// Entries returns the entries recorded so far.
+func (r *Recorder) Entries() []*logrus.Entry {
+	return r.hook.AllEntries()
+}
+
+// Reset forgets the recorded entries.
+func (r *Recorder) Reset() {
+	r.hook.Reset()
+}
+
+// EntriesForRequest returns the entries with the request ID id.
+func (r *Recorder) EntriesForRequest(id string) []*logrus.Entry {
+	field, _ := bzlogging.RequestIDTorequestIDField(id)
+	var entries []*logrus.Entry
+	for _, e := range r.Entries() {
+		if e.Data[field] == id {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// CompletionLines returns the entries logged by the logging middlewares when a request ends.
+func (r *Recorder) CompletionLines() []*logrus.Entry {
+	var entries []*logrus.Entry
+	for _, e := range r.Entries() {
+		if isCompletion(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// AssertAllHaveField asserts that there are entries and all of them have the field key equal to value.
+func (r *Recorder) AssertAllHaveField(t testing.TB, key string, value interface{}) bool {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return assert.Fail(t, "bztest: no entries recorded")
+	}
+	for _, e := range entries {
+		v, ok := e.Data[key]
+		if !ok {
+			return assert.Fail(t, "bztest: entry without the expected field", "%q has no %s", e.Message, key)
+		}
+		if !assert.ObjectsAreEqualValues(value, v) {
+			return assert.Fail(t, "bztest: entry with an unexpected field value", "%q has %s=%v, expected %v", e.Message, key, v, value)
+		}
+	}
+	return true
+}
+
+// AssertCompletionLine asserts that exactly one completion line was logged, at level and with status, compared to
+// the http.status of echo requests or to the grpc.code of gRPC calls, as a codes.Code or its name.
+func (r *Recorder) AssertCompletionLine(t testing.TB, level logrus.Level, status interface{}) bool {
+	lines := r.CompletionLines()
+	if len(lines) != 1 {
+		return assert.Fail(t, "bztest: unexpected number of completion lines", "expected 1, got %d", len(lines))
+	}
+	line := lines[0]
+	if line.Level != level {
+		return assert.Fail(t, "bztest: completion line with an unexpected level", "expected %s, got %s", level, line.Level)
+	}
+	if code, ok := line.Data[HTTPStatusField]; ok {
+		if !assert.ObjectsAreEqualValues(status, code) {
+			return assert.Fail(t, "bztest: completion line with an unexpected status", "expected %v, got %v", status, code)
+		}
+		return true
+	}
+	if code, ok := line.Data[GRPCCodeField]; ok {
+		if fmt.Sprint(status) != fmt.Sprint(code) {
+			return assert.Fail(t, "bztest: completion line with an unexpected code", "expected %v, got %v", status, code)
+		}
+		return true
+	}
+	return assert.Fail(t, "bztest: completion line without status", "%v", line.Data)
+}
+
+func isCompletion(e *logrus.Entry) bool {
+	for _, msg := range CompletionMessages {
+		if e.Message == msg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package bztest_test
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging/bztest"
+)
+
+func TestRecorder(t *testing.T) {
+	r := bztest.NewRecorder()
+	r.Entry().WithField("requestID", "foo").Debug("start")
+	r.Entry().WithFields(logrus.Fields{"requestID": "foo", "http.status": 404}).Warn("finished http call")
+	r.Entry().WithField("requestID", "bar").Info("other")
+
+	assert.Len(t, r.Entries(), 3)
+	assert.Len(t, r.EntriesForRequest("foo"), 2)
+	assert.Len(t, r.CompletionLines(), 1)
+	r.AssertCompletionLine(t, logrus.WarnLevel, 404)
+
+	mock := &testing.T{}
+	assert.False(t, r.AssertAllHaveField(mock, "requestID", "foo"))
+	assert.False(t, r.AssertAllHaveField(mock, "missing", "foo"))
+	assert.False(t, r.AssertCompletionLine(mock, logrus.InfoLevel, 404))
+	assert.False(t, r.AssertCompletionLine(mock, logrus.WarnLevel, 200))
+
+	r.Reset()
+	assert.Empty(t, r.Entries())
+	assert.False(t, r.AssertAllHaveField(mock, "requestID", "foo"), "no entries")
+	assert.False(t, r.AssertCompletionLine(mock, logrus.WarnLevel, 404), "no completion line")
+}
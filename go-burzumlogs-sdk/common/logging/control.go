@@ -0,0 +1,161 @@
+package bzlogging
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LeveledHook is a hook with its own minimum level, such as the logzum hook.
+type LeveledHook interface {
+	Level() logrus.Level
+	SetLevel(level logrus.Level) error
+}
+
+// LevelState is the current levels of a LevelControl.
+type LevelState struct {
+	Logger logrus.Level
+	Hooks  []logrus.Level
+	// RevertAt is when the levels go back to Previous, zero when no change is pending revert.
+	RevertAt time.Time
+	Previous *LevelState
+}
+
+// LevelControl changes the level of a logger and of its leveled hooks at runtime, logging an audit entry
+// for each change.
+type LevelControl struct {
+	mu sync.Mutex
+
+	logger *logrus.Logger
+
+	hooks []LeveledHook
+
+	revert *time.Timer
+
+	revertAt time.Time
+
+	// previous holds the levels before the change pending revert
+	previous *LevelState
+}
+
+// NewLevelControl controls the level of logger and hooks.
+func NewLevelControl(logger *logrus.Logger, hooks ...LeveledHook) *LevelControl {
+	return &LevelControl{logger: logger, hooks: hooks}
+}
+
+// Levels returns the current levels.
+func (c *LevelControl) Levels() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state()
+}
+
+func (c *LevelControl) state() LevelState {
+	s := LevelState{
+		Logger:   c.logger.GetLevel(),
+		Hooks:    make([]logrus.Level, len(c.hooks)),
+		RevertAt: c.revertAt,
+		Previous: c.previous,
+	}
+	for i, h := range c.hooks {
+		s.Hooks[i] = h.Level()
+	}
+	return s
+}
+
+// SetLevel sets level to the logger and the hooks on behalf of actor. If ttl is positive the levels before the
+// change go back after it, otherwise the change is permanent and any pending revert is cancelled. The levels and
+// the pending revert are kept when a hook refuses the level.
+func (c *LevelControl) SetLevel(actor string, level logrus.Level, ttl time.Duration) (LevelState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	before := c.state()
+	if err := c.apply(level, nil); err != nil {
+		return c.state(), err
+	}
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+	}
+
+	if ttl > 0 {
+		if c.previous == nil {
+			// a new change keeps reverting to the levels before the first one
+			previous := before
+			previous.Previous = nil
+			previous.RevertAt = time.Time{}
+			c.previous = &previous
+		}
+		c.revertAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() { c.expire(timer) })
+		c.revert = timer
+	} else {
+		c.previous = nil
+		c.revertAt = time.Time{}
+	}
+
+	fields := logrus.Fields{
+		"audit.actor":    actor,
+		"audit.action":   "set_level",
+		"level.previous": before.Logger.String(),
+		"level.new":      level.String(),
+	}
+	if ttl > 0 {
+		fields["level.ttl"] = ttl.String()
+	}
+	c.audit(fields, "log level changed")
+	return c.state(), nil
+}
+
+func (c *LevelControl) expire(timer *time.Timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.revert != timer || c.previous == nil {
+		return
+	}
+
+	previous := *c.previous
+	c.revert = nil
+	c.previous = nil
+	c.revertAt = time.Time{}
+	if err := c.apply(previous.Logger, previous.Hooks); err != nil {
+		c.logger.WithError(err).Error("unable to revert the log level")
+		return
+	}
+	c.audit(logrus.Fields{
+		"audit.actor":  "ttl",
+		"audit.action": "revert_level",
+		"level.new":    previous.Logger.String(),
+	}, "log level reverted")
+}
+
+// apply sets level to the logger and hookLevels, or level when nil, to the hooks. The hooks are set first, and
+// when one fails the ones already set go back to their levels, leaving the logger unchanged.
+func (c *LevelControl) apply(level logrus.Level, hookLevels []logrus.Level) error {
+	previous := make([]logrus.Level, len(c.hooks))
+	for i, h := range c.hooks {
+		previous[i] = h.Level()
+	}
+	for i, h := range c.hooks {
+		l := level
+		if hookLevels != nil {
+			l = hookLevels[i]
+		}
+		if err := h.SetLevel(l); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				c.hooks[j].SetLevel(previous[j])
+			}
+			return err
+		}
+	}
+	c.logger.SetLevel(level)
+	return nil
+}
+
+// audit logs the change at warning level, even if the logger and its hooks are set to a quieter one.
+func (c *LevelControl) audit(fields logrus.Fields, msg string) {
+	WithLevel(logrus.NewEntry(c.logger), logrus.WarnLevel).WithFields(fields).Warn(msg)
+}
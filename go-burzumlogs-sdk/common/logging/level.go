@@ -0,0 +1,104 @@
+package bzlogging
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoweredHook is a hook filtering the entries by its own level, such as the logzum hook, that provides a
+// hook firing the entries down to level too.
+type LoweredHook interface {
+	Lowered(level logrus.Level) logrus.Hook
+}
+
+// WithLevel returns a copy of entry bound to a logger that logs at least at level.
+//
+// The logger shares the output, formatter and hooks of the entry logger, each hook also fires for
+// the levels between its most verbose one and level, so the lowered entries reach the same hooks.
+// The LoweredHook hooks are replaced by their lowered ones. The logger writes to the output the entry
+// logger has at the time, under the lock of the entry logger, so their lines don't interleave.
+func WithLevel(entry *logrus.Entry, level logrus.Level) *logrus.Entry {
+	loggerLevel := entry.Logger.GetLevel()
+	if level > loggerLevel {
+		loggerLevel = level
+	}
+	logger := &logrus.Logger{
+		Out:       &parentWriter{parent: entry.Logger, mu: loggerMutex(entry.Logger)},
+		Formatter: entry.Logger.Formatter,
+		Hooks:     levelHooks(entry.Logger.Hooks, level),
+		Level:     loggerLevel,
+	}
+	return logrus.NewEntry(logger).WithFields(entry.Data)
+}
+
+// parentWriter writes to the output of parent holding mu, the lock parent writes its own entries with.
+type parentWriter struct {
+	parent *logrus.Logger
+	mu     *sync.Mutex
+}
+
+func (w *parentWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.parent.Out.Write(p)
+}
+
+// unsharedMu serializes the writes of the lowered loggers when the lock of their parent can't be found.
+var unsharedMu sync.Mutex
+
+// loggerMutex returns the lock logrus holds while a logger writes, the first field of its unexported mu.
+func loggerMutex(logger *logrus.Logger) *sync.Mutex {
+	mu := reflect.ValueOf(logger).Elem().FieldByName("mu")
+	if !mu.IsValid() || mu.Kind() != reflect.Struct || mu.NumField() == 0 || mu.Type().Field(0).Type != reflect.TypeOf(sync.Mutex{}) {
+		return &unsharedMu
+	}
+	return (*sync.Mutex)(unsafe.Pointer(mu.Field(0).UnsafeAddr()))
+}
+
+func levelHooks(hooks logrus.LevelHooks, level logrus.Level) logrus.LevelHooks {
+	lowered := make(logrus.LevelHooks, len(hooks))
+	for l, hs := range hooks {
+		for _, h := range hs {
+			lowered[l] = append(lowered[l], lowerHook(h, level))
+		}
+	}
+
+	// logrus.AllLevels goes from the most severe to the most verbose level.
+	var seen []logrus.Hook
+	for i := len(logrus.AllLevels) - 1; i >= 0; i-- {
+		for _, h := range hooks[logrus.AllLevels[i]] {
+			if containsHook(seen, h) {
+				continue
+			}
+			seen = append(seen, h)
+			for _, l := range logrus.AllLevels[i+1:] {
+				if l <= level {
+					lowered[l] = append(lowered[l], lowerHook(h, level))
+				}
+			}
+		}
+	}
+	return lowered
+}
+
+func lowerHook(hook logrus.Hook, level logrus.Level) logrus.Hook {
+	if h, ok := hook.(LoweredHook); ok {
+		return h.Lowered(level)
+	}
+	return hook
+}
+
+func containsHook(hooks []logrus.Hook, hook logrus.Hook) bool {
+	if !reflect.TypeOf(hook).Comparable() {
+		return false
+	}
+	for _, h := range hooks {
+		if reflect.TypeOf(h) == reflect.TypeOf(hook) && h == hook {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package tracecontext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Format identifies a trace context propagation format.
+type Format int
+
+const (
+	// W3C is the W3C trace-context `traceparent` header.
+	W3C Format = iota
+	// B3 is the Zipkin B3 format, both multi (`X-B3-TraceId`) and single (`b3`) header.
+	B3
+)
+
+const (
+	// TraceparentHeader is the W3C trace-context header name.
+	TraceparentHeader = "traceparent"
+	// B3TraceIDHeader is the B3 trace id header name.
+	B3TraceIDHeader = "X-B3-TraceId"
+	// B3SpanIDHeader is the B3 span id header name.
+	B3SpanIDHeader = "X-B3-SpanId"
+	// B3ParentSpanIDHeader is the B3 parent span id header name.
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	// B3SampledHeader is the B3 sampling decision header name.
+	B3SampledHeader = "X-B3-Sampled"
+	// B3FlagsHeader is the B3 debug flag header name.
+	B3FlagsHeader = "X-B3-Flags"
+	// B3Header is the B3 single header name.
+	B3Header = "b3"
+)
+
+var (
+	// DefaultFormats are the formats parsed when none is given, W3C takes precedence over B3.
+	DefaultFormats = []Format{W3C, B3}
+
+	zeroTraceID = strings.Repeat("0", 32)
+	zeroSpanID  = strings.Repeat("0", 16)
+)
+
+type ctxMarker struct{}
+
+var (
+	ctxMarkerKey = &ctxMarker{}
+)
+
+// TraceContext is the trace correlation propagated between services.
+type TraceContext struct {
+	// TraceID is the 32 lowercase hex characters trace id.
+	TraceID string
+	// SpanID is the 16 lowercase hex characters span id of this hop.
+	SpanID string
+	// ParentSpanID is the span id of the caller, empty on root contexts.
+	ParentSpanID string
+	// Sampled reports whether the caller recorded the trace.
+	Sampled bool
+}
+
+// New returns a new root trace context.
+func New() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+	}
+}
+
+// Child returns a trace context for the next hop, sharing the trace id and sampling decision.
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID:      tc.TraceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: tc.SpanID,
+		Sampled:      tc.Sampled,
+	}
+}
+
+// Traceparent returns the W3C `traceparent` header value of the trace context.
+func (tc TraceContext) Traceparent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+// ParseTraceparent parses a W3C `traceparent` header value.
+func ParseTraceparent(v string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+	if !isHex(traceID, 32) || traceID == zeroTraceID || !isHex(spanID, 16) || spanID == zeroSpanID || !isHex(flags, 2) {
+		return TraceContext{}, false
+	}
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: b[0]&0x01 == 0x01,
+	}, true
+}
+
+// ParseB3 parses the B3 single header or, when absent, the B3 multi headers returned by get.
+func ParseB3(get func(key string) string) (TraceContext, bool) {
+	if single := get(B3Header); single != "" {
+		return parseB3Single(single)
+	}
+	return parseB3(
+		get(B3TraceIDHeader),
+		get(B3SpanIDHeader),
+		get(B3ParentSpanIDHeader),
+		get(B3SampledHeader) == "1" || strings.EqualFold(get(B3SampledHeader), "true") || get(B3FlagsHeader) == "1",
+	)
+}
+
+func parseB3Single(v string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return TraceContext{}, false
+	}
+	var sampled bool
+	var parentSpanID string
+	if len(parts) > 2 {
+		sampled = parts[2] == "1" || parts[2] == "d"
+	}
+	if len(parts) > 3 {
+		parentSpanID = parts[3]
+	}
+	return parseB3(parts[0], parts[1], parentSpanID, sampled)
+}
+
+func parseB3(traceID, spanID, parentSpanID string, sampled bool) (TraceContext, bool) {
+	traceID = strings.ToLower(traceID)
+	spanID = strings.ToLower(spanID)
+	parentSpanID = strings.ToLower(parentSpanID)
+	if isHex(traceID, 16) {
+		// 64 bit trace ids are left padded to the 128 bit used by trace-context.
+		traceID = zeroSpanID + traceID
+	}
+	if !isHex(traceID, 32) || traceID == zeroTraceID || !isHex(spanID, 16) || spanID == zeroSpanID {
+		return TraceContext{}, false
+	}
+	if parentSpanID != "" && !isHex(parentSpanID, 16) {
+		return TraceContext{}, false
+	}
+	return TraceContext{
+		TraceID:      traceID,
+		SpanID:       spanID,
+		ParentSpanID: parentSpanID,
+		Sampled:      sampled,
+	}, true
+}
+
+// Parse parses the first trace context found by get, trying formats in order.
+func Parse(get func(key string) string, formats []Format) (TraceContext, bool) {
+	for _, f := range formats {
+		switch f {
+		case W3C:
+			if tc, ok := ParseTraceparent(get(TraceparentHeader)); ok {
+				return tc, true
+			}
+		case B3:
+			if tc, ok := ParseB3(get); ok {
+				return tc, true
+			}
+		}
+	}
+	return TraceContext{}, false
+}
+
+// Continue returns the trace context of this hop, a child of the incoming one found by get,
+// or a new root when there is none.
+func Continue(get func(key string) string, formats []Format) TraceContext {
+	if parent, ok := Parse(get, formats); ok {
+		return parent.Child()
+	}
+	return New()
+}
+
+// Extract takes the call-scoped trace context from context.
+func Extract(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(ctxMarkerKey).(TraceContext)
+	return tc, ok
+}
+
+// Inject the trace context.
+func Inject(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, ctxMarkerKey, tc)
+}
+
+func isHex(s string, size int) bool {
+	if len(s) != size {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	for {
+		rand.Read(b)
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
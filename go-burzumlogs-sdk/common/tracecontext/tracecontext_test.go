@@ -0,0 +1,88 @@
+package tracecontext_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tcase := range []struct {
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true, sampled: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ok: true},
+		{value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", ok: true, sampled: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"},
+		{value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{value: ""},
+	} {
+		tc, ok := tracecontext.ParseTraceparent(tcase.value)
+		assert.Equal(t, tcase.ok, ok, tcase.value)
+		if ok {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID, tcase.value)
+			assert.Equal(t, "00f067aa0ba902b7", tc.SpanID, tcase.value)
+			assert.Equal(t, tcase.sampled, tc.Sampled, tcase.value)
+		}
+	}
+}
+
+func TestParseB3(t *testing.T) {
+	h := http.Header{}
+	h.Set(tracecontext.B3TraceIDHeader, "A3CE929D0E0E4736")
+	h.Set(tracecontext.B3SpanIDHeader, "00f067aa0ba902b7")
+	h.Set(tracecontext.B3SampledHeader, "1")
+
+	tc, ok := tracecontext.ParseB3(h.Get)
+	assert.True(t, ok)
+	assert.Equal(t, "0000000000000000a3ce929d0e0e4736", tc.TraceID, "64 bit trace ids must be padded")
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+	assert.True(t, tc.Sampled)
+
+	h = http.Header{}
+	h.Set(tracecontext.B3Header, "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d-05e3ac9a4f6e3b90")
+
+	tc, ok = tracecontext.ParseB3(h.Get)
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "05e3ac9a4f6e3b90", tc.ParentSpanID)
+	assert.True(t, tc.Sampled, "debug must be sampled")
+
+	h.Set(tracecontext.B3Header, "0")
+	_, ok = tracecontext.ParseB3(h.Get)
+	assert.False(t, ok, "sampling only single header has no context")
+}
+
+func TestContinue(t *testing.T) {
+	h := http.Header{}
+	h.Set(tracecontext.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set(tracecontext.B3TraceIDHeader, "a3ce929d0e0e4736")
+	h.Set(tracecontext.B3SpanIDHeader, "00f067aa0ba902b7")
+
+	tc := tracecontext.Continue(h.Get, tracecontext.DefaultFormats)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID, "W3C must take precedence")
+	assert.Equal(t, "00f067aa0ba902b7", tc.ParentSpanID)
+	assert.NotEqual(t, "00f067aa0ba902b7", tc.SpanID, "a new span id must be created for this hop")
+	assert.True(t, tc.Sampled)
+
+	next, ok := tracecontext.ParseTraceparent(tc.Traceparent())
+	assert.True(t, ok)
+	assert.Equal(t, tc.TraceID, next.TraceID)
+	assert.Equal(t, tc.SpanID, next.SpanID)
+
+	tc = tracecontext.Continue(h.Get, []tracecontext.Format{tracecontext.B3})
+	assert.Equal(t, "0000000000000000a3ce929d0e0e4736", tc.TraceID)
+
+	tc = tracecontext.Continue(http.Header{}.Get, tracecontext.DefaultFormats)
+	_, ok = tracecontext.ParseTraceparent(tc.Traceparent())
+	assert.True(t, ok, "a new root must be created without incoming headers")
+	assert.Empty(t, tc.ParentSpanID)
+}
@@ -0,0 +1,15 @@
+package bztracing
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// JaegerExtractor extracts the ids from Jaeger span contexts.
+var JaegerExtractor SpanExtractor = SpanExtractorFunc(func(sc opentracing.SpanContext) (string, string, bool) {
+	jsc, ok := sc.(jaeger.SpanContext)
+	if !ok || !jsc.IsValid() {
+		return "", "", false
+	}
+	return jsc.TraceID().String(), jsc.SpanID().String(), true
+})
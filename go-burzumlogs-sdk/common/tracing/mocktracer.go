@@ -0,0 +1,17 @@
+package bztracing
+
+import (
+	"strconv"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// MockExtractor extracts the ids from mocktracer span contexts, it is meant to be used in tests.
+var MockExtractor SpanExtractor = SpanExtractorFunc(func(sc opentracing.SpanContext) (string, string, bool) {
+	msc, ok := sc.(mocktracer.MockSpanContext)
+	if !ok {
+		return "", "", false
+	}
+	return strconv.Itoa(msc.TraceID), strconv.Itoa(msc.SpanID), true
+})
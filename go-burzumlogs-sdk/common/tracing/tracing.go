@@ -0,0 +1,70 @@
+package bztracing
+
+import (
+	"golang.org/x/net/context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+)
+
+var (
+	// TraceIDField is the log field used to carry the trace id of the active span.
+	TraceIDField = "trace.id"
+
+	// SpanIDField is the log field used to carry the span id of the active span.
+	SpanIDField = "span.id"
+
+	// DefaultExtractors are the extractors used when none is given to the logging middlewares.
+	DefaultExtractors = []SpanExtractor{JaegerExtractor}
+)
+
+// SpanExtractor reads the trace and span ids from a tracer specific span context.
+type SpanExtractor interface {
+	Extract(sc opentracing.SpanContext) (traceID, spanID string, ok bool)
+}
+
+// SpanExtractorFunc is an adapter to allow the use of ordinary functions as SpanExtractor.
+type SpanExtractorFunc func(sc opentracing.SpanContext) (traceID, spanID string, ok bool)
+
+// Extract calls f(sc).
+func (f SpanExtractorFunc) Extract(sc opentracing.SpanContext) (traceID, spanID string, ok bool) {
+	return f(sc)
+}
+
+// IDs returns the trace and span ids of the active span in context.
+//
+// The extractors are tried in order and the first one that recognizes the span context wins.
+func IDs(ctx context.Context, extractors []SpanExtractor) (traceID, spanID string, ok bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return "", "", false
+	}
+	sc := span.Context()
+	for _, e := range extractors {
+		if traceID, spanID, ok = e.Extract(sc); ok {
+			return traceID, spanID, true
+		}
+	}
+	return "", "", false
+}
+
+// Fields returns the `trace.id` and `span.id` log fields of the active span in context.
+//
+// If there is no active span, or no extractor recognizes it, the propagated trace context is used instead.
+// If there is neither, nil is returned.
+func Fields(ctx context.Context, extractors []SpanExtractor) logrus.Fields {
+	traceID, spanID, ok := IDs(ctx, extractors)
+	if !ok {
+		tc, found := tracecontext.Extract(ctx)
+		if !found {
+			return nil
+		}
+		traceID, spanID = tc.TraceID, tc.SpanID
+	}
+	return logrus.Fields{
+		TraceIDField: traceID,
+		SpanIDField:  spanID,
+	}
+}
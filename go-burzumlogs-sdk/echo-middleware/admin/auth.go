@@ -0,0 +1,33 @@
+package echo_admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// BearerToken authorizes the requests with an `Authorization: Bearer <token>` header, tokens maps each
+// accepted token to the actor name logged in the audit entries.
+func BearerToken(tokens map[string]string) Authorizer {
+	return func(c echo.Context) (string, error) {
+		auth := c.Request().Header.Get(echo.HeaderAuthorization)
+		const prefix = "Bearer "
+		if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			return "", echo.NewHTTPError(http.StatusUnauthorized, "bearer token required")
+		}
+		given := []byte(auth[len(prefix):])
+
+		actor, found := "", false
+		for token, name := range tokens {
+			if subtle.ConstantTimeCompare(given, []byte(token)) == 1 {
+				actor, found = name, true
+			}
+		}
+		if !found {
+			return "", echo.NewHTTPError(http.StatusForbidden, "invalid token")
+		}
+		return actor, nil
+	}
+}
@@ -0,0 +1,99 @@
+package echo_admin
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+const actorKey = "echo_admin.actor"
+
+// LevelResponse is the body of the level endpoints.
+type LevelResponse struct {
+	Logger string   `json:"logger"`
+	Hooks  []string `json:"hooks"`
+	// RevertAt and RevertTo are set while a change with a TTL is active.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+	RevertTo string     `json:"revert_to,omitempty"`
+}
+
+// LevelRequest is the body of a level change, TTL is a duration such as "10m".
+type LevelRequest struct {
+	Level string `json:"level" form:"level"`
+	TTL   string `json:"ttl" form:"ttl"`
+}
+
+// LogLevels registers on g the handlers reading (GET /level) and changing (PUT or POST /level) the levels
+// controlled by control, e.g. on e.Group("/_logs").
+func LogLevels(g *echo.Group, control *bzlogging.LevelControl, opts ...Option) {
+	o := evaluateOpt(opts)
+	auth := authorize(o)
+
+	g.GET("/level", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, newLevelResponse(control.Levels()))
+	}, auth)
+
+	set := func(c echo.Context) error {
+		var req LevelRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		level, err := logrus.ParseLevel(req.Level)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid ttl %q", req.TTL))
+			}
+		}
+		if o.maxTTL > 0 && (ttl == 0 || ttl > o.maxTTL) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ttl must be between 0 and %s", o.maxTTL))
+		}
+
+		actor, _ := c.Get(actorKey).(string)
+		state, err := control.SetLevel(actor, level, ttl)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, newLevelResponse(state))
+	}
+	g.PUT("/level", set, auth)
+	g.POST("/level", set, auth)
+}
+
+func authorize(o *options) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actor, err := o.authorizer(c)
+			if err != nil {
+				return err
+			}
+			c.Set(actorKey, actor)
+			return next(c)
+		}
+	}
+}
+
+func newLevelResponse(s bzlogging.LevelState) LevelResponse {
+	res := LevelResponse{
+		Logger: s.Logger.String(),
+		Hooks:  make([]string, len(s.Hooks)),
+	}
+	for i, l := range s.Hooks {
+		res.Hooks[i] = l.String()
+	}
+	if !s.RevertAt.IsZero() {
+		res.RevertAt = &s.RevertAt
+	}
+	if s.Previous != nil {
+		res.RevertTo = s.Previous.Logger.String()
+	}
+	return res
+}
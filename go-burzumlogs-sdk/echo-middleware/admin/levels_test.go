@@ -0,0 +1,213 @@
+package echo_admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/admin"
+)
+
+type leveledHook struct {
+	mu    sync.Mutex
+	level logrus.Level
+	err   error
+}
+
+func (h *leveledHook) Level() logrus.Level {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.level
+}
+
+func (h *leveledHook) SetLevel(level logrus.Level) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	h.level = level
+	return nil
+}
+
+func (h *leveledHook) fail(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = err
+}
+
+type adminServer struct {
+	e      *echo.Echo
+	logger *logrus.Logger
+	audit  *test.Hook
+	hook   *leveledHook
+}
+
+func newAdminServer(opts ...echo_admin.Option) *adminServer {
+	logger, audit := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	hook := &leveledHook{level: logrus.WarnLevel}
+
+	e := echo.New()
+	echo_admin.LogLevels(e.Group("/_logs"), bzlogging.NewLevelControl(logger, hook), opts...)
+	return &adminServer{e: e, logger: logger, audit: audit, hook: hook}
+}
+
+func (s *adminServer) do(method, body, token string) (*httptest.ResponseRecorder, echo_admin.LevelResponse) {
+	req := httptest.NewRequest(method, "/_logs/level", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	var res echo_admin.LevelResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+var tokens = echo_admin.WithAuthorizer(echo_admin.BearerToken(map[string]string{"s3cr3t": "alice"}))
+
+func TestLogLevelsDeniedByDefault(t *testing.T) {
+	s := newAdminServer()
+
+	rec, _ := s.do(echo.GET, "", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec, _ = s.do(echo.PUT, `{"level":"debug"}`, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, logrus.InfoLevel, s.logger.GetLevel())
+}
+
+func TestLogLevelsBearerToken(t *testing.T) {
+	s := newAdminServer(tokens)
+
+	rec, _ := s.do(echo.GET, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = s.do(echo.GET, "", "wrong")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec, res := s.do(echo.GET, "", "s3cr3t")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo_admin.LevelResponse{Logger: "info", Hooks: []string{"warning"}}, res)
+}
+
+func TestLogLevelsSet(t *testing.T) {
+	s := newAdminServer(tokens)
+
+	rec, res := s.do(echo.PUT, `{"level":"debug"}`, "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, echo_admin.LevelResponse{Logger: "debug", Hooks: []string{"debug"}}, res)
+	assert.Equal(t, logrus.DebugLevel, s.logger.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, s.hook.Level())
+
+	entry := s.audit.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "log level changed", entry.Message)
+	assert.Equal(t, logrus.Fields{
+		"audit.actor":    "alice",
+		"audit.action":   "set_level",
+		"level.previous": "info",
+		"level.new":      "debug",
+	}, entry.Data)
+}
+
+func TestLogLevelsAuditWhenQuiet(t *testing.T) {
+	s := newAdminServer(tokens)
+
+	rec, _ := s.do(echo.POST, `{"level":"error"}`, "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	entry := s.audit.LastEntry()
+	require.NotNil(t, entry, "the audit entry must be logged above the new level")
+	assert.Equal(t, "error", entry.Data["level.new"])
+}
+
+func TestLogLevelsTTL(t *testing.T) {
+	s := newAdminServer(tokens)
+
+	rec, res := s.do(echo.PUT, `{"level":"debug","ttl":"100ms"}`, "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "info", res.RevertTo)
+	require.NotNil(t, res.RevertAt)
+
+	// a second change keeps reverting to the levels before the first one
+	rec, res = s.do(echo.PUT, `{"level":"trace","ttl":"100ms"}`, "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "info", res.RevertTo)
+
+	assert.Eventually(t, func() bool { return s.logger.GetLevel() == logrus.InfoLevel }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, logrus.WarnLevel, s.hook.Level())
+
+	entry := s.audit.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "log level reverted", entry.Message)
+	assert.Equal(t, "ttl", entry.Data["audit.actor"])
+
+	_, res = s.do(echo.GET, "", "s3cr3t")
+	assert.Nil(t, res.RevertAt)
+}
+
+func TestLogLevelsPermanentChangeCancelsRevert(t *testing.T) {
+	s := newAdminServer(tokens)
+
+	s.do(echo.PUT, `{"level":"debug","ttl":"50ms"}`, "s3cr3t")
+	_, res := s.do(echo.PUT, `{"level":"warning"}`, "s3cr3t")
+	assert.Empty(t, res.RevertTo)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, logrus.WarnLevel, s.logger.GetLevel())
+}
+
+func TestLogLevelsInvalid(t *testing.T) {
+	s := newAdminServer(tokens, echo_admin.WithMaxTTL(time.Hour))
+
+	for _, body := range []string{
+		`{"level":"loud","ttl":"1m"}`,
+		`{"level":"debug","ttl":"soon"}`,
+		`{"level":"debug","ttl":"2h"}`,
+		`{"level":"debug"}`,
+	} {
+		rec, _ := s.do(echo.PUT, body, "s3cr3t")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	assert.Equal(t, logrus.InfoLevel, s.logger.GetLevel())
+}
+
+func TestLogLevelsHookFailureKeepsLevels(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	first := &leveledHook{level: logrus.WarnLevel}
+	second := &leveledHook{level: logrus.WarnLevel}
+	control := bzlogging.NewLevelControl(logger, first, second)
+
+	_, err := control.SetLevel("alice", logrus.DebugLevel, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	second.fail(errors.New("invalid config"))
+	state, err := control.SetLevel("alice", logrus.TraceLevel, 0)
+	assert.Error(t, err)
+	assert.Equal(t, logrus.DebugLevel, state.Logger, "the logger level is kept")
+	assert.Equal(t, []logrus.Level{logrus.DebugLevel, logrus.DebugLevel}, state.Hooks, "the hooks already set go back")
+	assert.False(t, state.RevertAt.IsZero(), "the pending revert is kept")
+
+	second.fail(nil)
+	assert.Eventually(t, func() bool { return logger.GetLevel() == logrus.InfoLevel }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, logrus.WarnLevel, first.Level())
+}
@@ -0,0 +1,54 @@
+package echo_admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Authorizer authorizes an admin request, returning who made it for the audit log.
+// The returned error is sent as the response, an *echo.HTTPError sets its status.
+type Authorizer func(c echo.Context) (actor string, err error)
+
+type options struct {
+	authorizer Authorizer
+	maxTTL     time.Duration
+}
+
+var (
+	defaultOptions = &options{
+		authorizer: DenyAll,
+	}
+)
+
+func evaluateOpt(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithAuthorizer customizes the function authorizing the requests, all of them are denied by default.
+func WithAuthorizer(a Authorizer) Option {
+	return func(o *options) {
+		o.authorizer = a
+	}
+}
+
+// WithMaxTTL customizes the longest TTL accepted, any TTL is accepted by default.
+// When set, changes without a TTL are refused.
+func WithMaxTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.maxTTL = ttl
+	}
+}
+
+// DenyAll refuses all the requests, it is the default authorizer.
+func DenyAll(c echo.Context) (string, error) {
+	return "", echo.NewHTTPError(http.StatusForbidden, "no authorizer configured")
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/labstack/echo"
 
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 )
 
 var (
@@ -26,7 +28,20 @@ func Logger(entry *logrus.Entry, opts ...Option) echo.MiddlewareFunc {
 				return next(c)
 			}
 			req := c.Request()
-			newCtx := newLoggerForHttpCall(req.Context(), entry, o, req.RequestURI, req.Method)
+			ctx := req.Context()
+			callEntry := entry
+			var buffer *bzlogging.Buffer
+			debugValue := req.Header.Get(debuglog.Header)
+			if debugValue != "" {
+				ctx = debuglog.Inject(ctx, debugValue)
+			}
+			if level, ok := debuglog.Verify(o.debugSecret, debugValue, time.Now()); ok {
+				callEntry = bzlogging.WithLevel(entry, level)
+			} else if o.buffered {
+				buffer = bzlogging.NewBuffer(entry, o.bufferLevel, o.bufferSize)
+				callEntry = buffer.Entry()
+			}
+			newCtx := newLoggerForHttpCall(ctx, callEntry, o, req.RequestURI, req.Method)
 			c.SetRequest(req.WithContext(newCtx))
 
 			res := c.Response()
@@ -68,6 +83,16 @@ func Logger(entry *logrus.Entry, opts ...Option) echo.MiddlewareFunc {
 
 			fields["http.bytes_out"] = strconv.FormatInt(res.Size, 10)
 
+			if buffer != nil {
+				if o.flushFunc(res.Status) {
+					if dropped := buffer.Flush(); dropped > 0 {
+						fields["buffer.dropped"] = dropped
+					}
+				} else {
+					buffer.Discard()
+				}
+			}
+
 			levelLogf(
 				bzlogging.Extract(newCtx).WithFields(fields), // re-extract logger from newCtx, as it may have extra fields that changed in the holder.
 				level,
@@ -98,13 +123,17 @@ func newLoggerForHttpCall(ctx context.Context, entry *logrus.Entry, o *options,
 
 	requestIDField, requestID := o.requestIDfunc(ctx)
 
-	callLog := entry.WithFields(
-		logrus.Fields{
-			KindField:      "server",
-			"http.uri":     uri,
-			"http.method":  method,
-			requestIDField: requestID,
-		})
+	fields := logrus.Fields{
+		KindField:      "server",
+		"http.uri":     uri,
+		"http.method":  method,
+		requestIDField: requestID,
+	}
+	for k, v := range bztracing.Fields(ctx, o.extractors) {
+		fields[k] = v
+	}
+
+	callLog := entry.WithFields(fields)
 
 	return bzlogging.Inject(ctx, callLog)
 }
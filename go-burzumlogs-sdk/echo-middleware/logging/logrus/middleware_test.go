@@ -1,10 +1,14 @@
 package echo_logzum_test
 
 import (
+	"bytes"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -13,10 +17,14 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/labstack/echo"
+	"github.com/opentracing/opentracing-go/mocktracer"
 
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/opentracing"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/requestid"
 )
 
@@ -145,3 +153,197 @@ func TestLogger(t *testing.T) {
 	assert.Nil(t, hook.LastEntry())
 
 }
+
+func TestLoggerWithSpan(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	entry := logrus.NewEntry(logger)
+
+	tracer := mocktracer.New()
+
+	middlewares := []echo.MiddlewareFunc{
+		echo_requestid.RequestID(),
+		echo_opentracing.Tracer(echo_opentracing.WithTracer(tracer)),
+		echo_logzum.Logger(entry, echo_logzum.WithSpanExtractors(bztracing.MockExtractor)),
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		bzlogging.Logger(c.Request().Context()).Info("test")
+		return c.String(http.StatusOK, "test")
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	h(c)
+
+	spans := tracer.FinishedSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, 2, len(hook.Entries))
+
+	sc := spans[0].Context().(mocktracer.MockSpanContext)
+	for _, entry := range hook.Entries {
+		assert.Equal(t, strconv.Itoa(sc.TraceID), entry.Data["trace.id"], "all lines must contain `trace.id`")
+		assert.Equal(t, strconv.Itoa(sc.SpanID), entry.Data["span.id"], "all lines must contain `span.id`")
+	}
+
+	// Without an active span
+	hook.Reset()
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+	h = echo_logzum.Logger(entry, echo_logzum.WithSpanExtractors(bztracing.MockExtractor))(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	h(c)
+
+	assert.Equal(t, 1, len(hook.Entries))
+	assert.NotContains(t, hook.LastEntry().Data, "trace.id")
+	assert.NotContains(t, hook.LastEntry().Data, "span.id")
+}
+
+func TestLoggerWithBuffer(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	entry := logrus.NewEntry(logger)
+
+	e := echo.New()
+
+	run := func(status int, opts ...echo_logzum.Option) {
+		hook.Reset()
+		c := e.NewContext(httptest.NewRequest(echo.GET, "/", nil), httptest.NewRecorder())
+		h := echo_logzum.Logger(entry, opts...)(func(c echo.Context) error {
+			l := bzlogging.Logger(c.Request().Context())
+			l.Debug("first")
+			l.Debug("second")
+			l.Info("info")
+			return c.String(status, "test")
+		})
+		h(c)
+	}
+
+	// Status 200 discards the buffered entries
+	run(http.StatusOK, echo_logzum.WithBuffer(logrus.InfoLevel, 0))
+
+	assert.Equal(t, 2, len(hook.Entries))
+	assert.Equal(t, "info", hook.Entries[0].Message)
+	assert.Equal(t, "finished http call", hook.Entries[1].Message)
+
+	// Status 5xx flushes the buffered entries before the completion line
+	run(http.StatusInternalServerError, echo_logzum.WithBuffer(logrus.InfoLevel, 0))
+
+	assert.Equal(t, 4, len(hook.Entries))
+	assert.Equal(t, "info", hook.Entries[0].Message)
+	assert.Equal(t, "first", hook.Entries[1].Message)
+	assert.Equal(t, logrus.DebugLevel, hook.Entries[1].Level)
+	assert.Equal(t, "second", hook.Entries[2].Message)
+	assert.Equal(t, "finished http call", hook.Entries[3].Message)
+	for _, entry := range hook.Entries {
+		assert.Equal(t, "/", entry.Data["http.uri"], "all lines must contain uri name")
+	}
+	assert.Nil(t, hook.LastEntry().Data["buffer.dropped"])
+
+	// The memory cap drops the oldest entries
+	run(http.StatusInternalServerError, echo_logzum.WithBuffer(logrus.InfoLevel, 100))
+
+	assert.Equal(t, 3, len(hook.Entries))
+	assert.Equal(t, "second", hook.Entries[1].Message)
+	assert.Equal(t, 1, hook.LastEntry().Data["buffer.dropped"])
+
+	// Custom flush predicate
+	run(http.StatusNotFound, echo_logzum.WithBuffer(logrus.InfoLevel, 0), echo_logzum.WithFlushOn(func(code int) bool {
+		return code >= 500
+	}))
+
+	assert.Equal(t, 2, len(hook.Entries))
+}
+
+func TestLoggerWithDebugHeader(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	entry := logrus.NewEntry(logger)
+
+	secret := []byte("secret")
+
+	e := echo.New()
+
+	run := func(value string) string {
+		hook.Reset()
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(debuglog.Header, value)
+		c := e.NewContext(req, httptest.NewRecorder())
+		var propagated string
+		h := echo_logzum.Logger(entry, echo_logzum.WithDebugSecret(secret), echo_logzum.WithBuffer(logrus.InfoLevel, 0))(func(c echo.Context) error {
+			bzlogging.Logger(c.Request().Context()).Debug("debug")
+			propagated, _ = debuglog.Extract(c.Request().Context())
+			return c.String(http.StatusOK, "test")
+		})
+		h(c)
+		return propagated
+	}
+
+	value := debuglog.Sign(secret, logrus.DebugLevel, time.Now().Add(time.Minute))
+	assert.Equal(t, value, run(value), "the header must be propagated in the context")
+	assert.Equal(t, 2, len(hook.Entries))
+	assert.Equal(t, "debug", hook.Entries[0].Message)
+	assert.Equal(t, logrus.DebugLevel, hook.Entries[0].Level)
+	assert.Equal(t, "/", hook.Entries[0].Data["http.uri"], "all lines must contain uri name")
+	assert.Equal(t, logrus.InfoLevel, logger.Level, "the level must only change for the request")
+
+	value = debuglog.Sign([]byte("other"), logrus.DebugLevel, time.Now().Add(time.Minute))
+	run(value)
+	assert.Equal(t, 1, len(hook.Entries), "invalid signatures must be ignored")
+
+	value = debuglog.Sign(secret, logrus.DebugLevel, time.Now().Add(-time.Minute))
+	run(value)
+	assert.Equal(t, 1, len(hook.Entries), "expired values must be ignored")
+}
+
+func TestLoggerWithDebugHeaderSharedOutput(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+
+	secret := []byte("secret")
+	value := debuglog.Sign(secret, logrus.DebugLevel, time.Now().Add(time.Minute))
+
+	e := echo.New()
+	h := echo_logzum.Logger(logrus.NewEntry(logger), echo_logzum.WithDebugSecret(secret))(func(c echo.Context) error {
+		bzlogging.Logger(c.Request().Context()).Debug("debug")
+		return c.String(http.StatusOK, "test")
+	})
+
+	// the request and the logger write to the same buffer, the writes must be serialized
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(debuglog.Header, value)
+			h(e.NewContext(req, httptest.NewRecorder()))
+		}()
+		go func() {
+			defer wg.Done()
+			logger.Info("concurrent")
+		}()
+	}
+	wg.Wait()
+
+	logger.Info("last")
+	assert.Equal(t, 31, bytes.Count(out.Bytes(), []byte("\n")))
+	assert.Equal(t, &out, logger.Out, "the output of the logger is not replaced")
+
+	// the lowered loggers follow the output set later
+	var later bytes.Buffer
+	logger.SetOutput(&later)
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set(debuglog.Header, value)
+	h(e.NewContext(req, httptest.NewRecorder()))
+	assert.Contains(t, later.String(), "msg=debug")
+}
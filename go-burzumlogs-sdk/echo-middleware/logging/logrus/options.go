@@ -3,6 +3,7 @@ package echo_logzum
 import (
 	"github.com/labstack/echo/middleware"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -11,6 +12,9 @@ var (
 		levelFunc:     nil,
 		skipper:       middleware.DefaultSkipper,
 		requestIDfunc: bzlogging.DefaultRequestIDfunc,
+		extractors:    bztracing.DefaultExtractors,
+		stackSize:     4 << 10, // 4 KB
+		flushFunc:     DefaultShouldFlush,
 	}
 )
 
@@ -22,6 +26,13 @@ type options struct {
 	levelFunc     CodeToLevel
 	skipper       middleware.Skipper
 	requestIDfunc bzlogging.RequestIDFromContext
+	extractors    []bztracing.SpanExtractor
+	stackSize     int
+	buffered      bool
+	bufferLevel   logrus.Level
+	bufferSize    int
+	flushFunc     ShouldFlush
+	debugSecret   []byte
 }
 
 func evaluateServerOpt(opts []Option) *options {
@@ -36,6 +47,9 @@ func evaluateServerOpt(opts []Option) *options {
 
 type Option func(*options)
 
+// ShouldFlush function determines whether the buffered entries of a request are flushed, given its status code.
+type ShouldFlush func(code int) bool
+
 // CodeToLevel function defines the mapping between gRPC return codes and interceptor log level.
 type CodeToLevel func(code int) logrus.Level
 
@@ -46,6 +60,13 @@ func WithRequestId(f bzlogging.RequestIDFromContext) Option {
 	}
 }
 
+// WithSpanExtractors customizes the extractors used to add the `trace.id` and `span.id` of the active span.
+func WithSpanExtractors(e ...bztracing.SpanExtractor) Option {
+	return func(o *options) {
+		o.extractors = e
+	}
+}
+
 // WithLevels customizes the function for mapping gRPC return codes and interceptor log level statements.
 func WithLevels(f CodeToLevel) Option {
 	return func(o *options) {
@@ -60,6 +81,46 @@ func WithSkipper(s middleware.Skipper) Option {
 	}
 }
 
+// WithStackSize customizes the size of the stack trace logged by Recover.
+func WithStackSize(size int) Option {
+	return func(o *options) {
+		o.stackSize = size
+	}
+}
+
+// WithBuffer enables the buffering of the request entries below level, which are only logged if the request fails.
+//
+// At most maxBytes are held per request, older entries are dropped first, DefaultBufferSize is used if maxBytes is zero.
+func WithBuffer(level logrus.Level, maxBytes int) Option {
+	return func(o *options) {
+		o.buffered = true
+		o.bufferLevel = level
+		o.bufferSize = maxBytes
+	}
+}
+
+// WithFlushOn customizes the function for deciding whether the buffered entries are flushed.
+func WithFlushOn(f ShouldFlush) Option {
+	return func(o *options) {
+		o.flushFunc = f
+	}
+}
+
+// WithDebugSecret enables the per-request log level of the `X-Debug-Log` header, signed with secret.
+//
+// The header value is created with debuglog.Sign, requests carrying a valid one are logged at its level
+// and are never buffered.
+func WithDebugSecret(secret []byte) Option {
+	return func(o *options) {
+		o.debugSecret = secret
+	}
+}
+
+// DefaultShouldFlush is the default implementation of ShouldFlush, it flushes the buffered entries of 4xx and 5xx responses.
+func DefaultShouldFlush(code int) bool {
+	return code >= 400
+}
+
 // DefaultCodeToLevel is the default implementation of Echo return codes to log levels for server side.
 func DefaultCodeToLevel(code int) logrus.Level {
 	switch {
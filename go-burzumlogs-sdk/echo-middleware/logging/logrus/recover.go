@@ -0,0 +1,70 @@
+package echo_logzum
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+var (
+	// PanicField is the log field used to carry the recovered panic value.
+	PanicField = "panic"
+
+	// StackField is the log field used to carry the stack trace of the recovered panic.
+	StackField = "stack"
+)
+
+// Recover returns a middleware that recovers from panics in the handler chain.
+//
+// The panic is logged at Error level through the context logger and returned as an error, which is
+// converted into a 500 and recorded by the logging and tracing middlewares, so Recover must be installed after them.
+func Recover(opts ...Option) echo.MiddlewareFunc {
+	o := evaluateServerOpt(opts)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if o.skipper(c) {
+				return next(c)
+			}
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				err = logPanic(c, o, r)
+			}()
+			return next(c)
+		}
+	}
+}
+
+func logPanic(c echo.Context, o *options, r interface{}) error {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+
+	stack := make([]byte, o.stackSize)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	ctx := c.Request().Context()
+	logger := bzlogging.Extract(ctx)
+	fields := logrus.Fields{
+		PanicField:      fmt.Sprint(r),
+		StackField:      string(stack),
+		logrus.ErrorKey: err,
+	}
+	if requestIDField, requestID := o.requestIDfunc(ctx); logger.Data[requestIDField] == nil {
+		fields[requestIDField] = requestID
+	}
+	logger.WithFields(fields).Error("recovered from panic")
+
+	return fmt.Errorf("panic: %v", err)
+}
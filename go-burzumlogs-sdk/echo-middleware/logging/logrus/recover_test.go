@@ -0,0 +1,84 @@
+package echo_logzum_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/labstack/echo"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/requestid"
+)
+
+func TestRecover(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	entry := logrus.NewEntry(logger)
+
+	opts := []echo_logzum.Option{
+		echo_logzum.WithRequestId(func(ctx context.Context) (string, interface{}) {
+			return bzlogging.RequestIDTorequestIDField(requestid.Extract(ctx))
+		}),
+	}
+
+	middlewares := []echo.MiddlewareFunc{
+		echo_requestid.RequestID(),
+		echo_logzum.Logger(entry, opts...),
+		echo_logzum.Recover(opts...),
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Add("X-Request-ID", `foo`)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		panic("boom")
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	assert.NotPanics(t, func() { h(c) })
+
+	assert.Equal(t, 500, rec.Code)
+	assert.Equal(t, 2, len(hook.Entries))
+
+	for _, entry := range hook.Entries {
+		assert.Equal(t, logrus.ErrorLevel, entry.Level)
+		assert.Equal(t, "foo", entry.Data["requestID"], "all lines must contain `requestID`")
+		assert.Equal(t, "/", entry.Data["http.uri"], "all lines must contain uri name")
+	}
+	assert.Equal(t, "recovered from panic", hook.Entries[0].Message)
+	assert.Equal(t, "boom", hook.Entries[0].Data["panic"])
+	assert.Contains(t, hook.Entries[0].Data["stack"], "goroutine", "the panic line must contain the stack trace")
+	assert.Equal(t, "finished http call", hook.Entries[1].Message)
+	assert.Equal(t, 500, hook.Entries[1].Data["http.status"])
+	hook.Reset()
+
+	// Without the logging middleware the request id is still logged
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	h = func(c echo.Context) error {
+		panic("boom")
+	}
+	h = echo_requestid.RequestID()(echo_logzum.Recover(opts...)(h))
+
+	bzlogging.L = entry
+	defer func() { bzlogging.L = logrus.NewEntry(logrus.StandardLogger()) }()
+	err := h(c)
+
+	assert.EqualError(t, err, "panic: boom")
+	assert.Equal(t, 1, len(hook.Entries))
+	assert.Equal(t, "foo", hook.LastEntry().Data["requestID"])
+}
@@ -0,0 +1,79 @@
+package echo_otel
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName is the name of the tracer created by the middleware.
+	InstrumentationName = "github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/otel"
+)
+
+// Tracer returns a middleware that traces HTTP requests with OpenTelemetry.
+func Tracer(opts ...Option) echo.MiddlewareFunc {
+	o := evaluateServerOpt(opts)
+	tracer := o.tracerProvider.Tracer(InstrumentationName, trace.WithSchemaURL(semconv.SchemaURL))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if o.skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			res := c.Response()
+
+			ctx := o.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+
+			scheme := "http"
+			if req.TLS != nil {
+				scheme = "https"
+			}
+
+			requestIDField, requestID := o.requestIDfunc(req.Context())
+
+			ctx, span := tracer.Start(
+				ctx,
+				req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPTarget(req.RequestURI),
+					semconv.HTTPRoute(route),
+					semconv.HTTPScheme(scheme),
+					semconv.NetHostName(req.Host),
+					semconv.HTTPUserAgent(req.UserAgent()),
+					semconv.HTTPClientIP(c.RealIP()),
+					attribute.String("http.referer", req.Referer()),
+					attribute.String(requestIDField, fmt.Sprint(requestID)),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			if err = next(c); err != nil {
+				span.RecordError(err)
+				c.Error(err)
+			}
+
+			span.SetAttributes(semconv.HTTPStatusCode(res.Status))
+			if res.Status >= 500 {
+				span.SetStatus(codes.Error, fmt.Sprintf("HTTP status code %d", res.Status))
+			}
+
+			return
+		}
+	}
+}
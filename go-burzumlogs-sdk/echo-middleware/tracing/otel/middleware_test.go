@@ -0,0 +1,107 @@
+package echo_otel_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/labstack/echo"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/otel"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/echo-middleware/tracing/requestid"
+)
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	opts := []echo_otel.Option{
+		echo_otel.WithRequestId(func(ctx context.Context) (string, interface{}) {
+			return bzlogging.RequestIDTorequestIDField(requestid.Extract(ctx))
+		}),
+		echo_otel.WithTracerProvider(tp),
+	}
+
+	middlewares := []echo.MiddlewareFunc{
+		echo_requestid.RequestID(),
+		echo_otel.Tracer(opts...),
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Add("X-Request-ID", `foo`)
+	req.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	var active trace.SpanContext
+	h := func(c echo.Context) error {
+		active = trace.SpanContextFromContext(c.Request().Context())
+		return c.String(http.StatusOK, "test")
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	// Status 200
+	h(c)
+
+	spans := exporter.GetSpans().Snapshots()
+	assert.Equal(t, 1, len(spans))
+
+	span := spans[0]
+	attrs := attributes(span)
+	assert.Equal(t, "GET /", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), "the span must continue the incoming trace")
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String(), "the span must be a child of the incoming span")
+	assert.Equal(t, span.SpanContext().SpanID(), active.SpanID(), "the span must be active in the handler context")
+	assert.Equal(t, "foo", attrs["requestID"].AsString(), "the span must contain `requestID`")
+	assert.Equal(t, "GET", attrs["http.method"].AsString(), "the span must contain method name")
+	assert.Equal(t, "/", attrs["http.target"].AsString(), "the span must contain target")
+	assert.Equal(t, int64(200), attrs["http.status_code"].AsInt64(), "the span must contain status")
+	assert.Equal(t, codes.Unset, span.Status().Code)
+	exporter.Reset()
+
+	// Status 5xx
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+	h = func(c echo.Context) error {
+		return errors.New("error")
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	h(c)
+
+	spans = exporter.GetSpans().Snapshots()
+	assert.Equal(t, 1, len(spans))
+
+	span = spans[0]
+	assert.False(t, span.Parent().IsValid(), "the span must be a root span without `traceparent`")
+	assert.Equal(t, int64(500), attributes(span)["http.status_code"].AsInt64(), "the span must contain status")
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, 1, len(span.Events()), "the error must be recorded")
+}
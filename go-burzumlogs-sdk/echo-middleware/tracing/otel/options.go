@@ -0,0 +1,66 @@
+package echo_otel
+
+import (
+	"github.com/labstack/echo/middleware"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+type options struct {
+	skipper        middleware.Skipper
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	requestIDfunc  bzlogging.RequestIDFromContext
+}
+
+var (
+	defaultOptions = &options{
+		skipper:       middleware.DefaultSkipper,
+		propagator:    propagation.TraceContext{},
+		requestIDfunc: bzlogging.DefaultRequestIDfunc,
+	}
+)
+
+func evaluateServerOpt(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.tracerProvider = otel.GetTracerProvider()
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithSkipper customizes the function for skip the requests.
+func WithSkipper(s middleware.Skipper) Option {
+	return func(o *options) {
+		o.skipper = s
+	}
+}
+
+// WithTracerProvider customizes the provider used to create the tracer, the global one is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator customizes the propagator used to extract the parent span, W3C `traceparent` is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+// WithRequestId customizes the function for get the request id.
+func WithRequestId(f bzlogging.RequestIDFromContext) Option {
+	return func(o *options) {
+		o.requestIDfunc = f
+	}
+}
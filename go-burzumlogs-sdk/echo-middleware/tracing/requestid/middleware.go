@@ -3,6 +3,7 @@ package echo_requestid
 import (
 	"github.com/labstack/echo"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
 )
 
 // RequestID returns a middleware that create or user requestid for HTTP requests.
@@ -28,6 +29,10 @@ func RequestID(opts ...Option) echo.MiddlewareFunc {
 
 			ctx := requestid.Inject(req.Context(), rid)
 
+			if o.formats != nil {
+				ctx = tracecontext.Inject(ctx, tracecontext.Continue(req.Header.Get, o.formats))
+			}
+
 			c.SetRequest(req.WithContext(ctx))
 
 			return next(c)
@@ -146,3 +146,75 @@ func TestRequestID(t *testing.T) {
 	assert.Nil(t, hook.LastEntry())
 
 }
+
+func TestRequestIDWithTraceContext(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	entry := logrus.NewEntry(logger)
+
+	middlewares := []echo.MiddlewareFunc{
+		echo_requestid.RequestID(echo_requestid.WithTraceContext()),
+		echo_logzum.Logger(entry),
+	}
+
+	e := echo.New()
+
+	for _, tcase := range []struct {
+		header  string
+		value   string
+		traceID string
+	}{
+		{
+			header:  "traceparent",
+			value:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			header:  "X-B3-TraceId",
+			value:   "a3ce929d0e0e4736",
+			traceID: "0000000000000000a3ce929d0e0e4736",
+		},
+		{
+			header:  "b3",
+			value:   "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	} {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Add(tcase.header, tcase.value)
+		req.Header.Add("X-B3-SpanId", "00f067aa0ba902b7")
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			bzlogging.Logger(c.Request().Context()).Info("test")
+			return c.String(http.StatusOK, "test")
+		}
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](h)
+		}
+		h(c)
+
+		assert.Equal(t, 2, len(hook.Entries))
+		for _, entry := range hook.Entries {
+			assert.Equal(t, tcase.traceID, entry.Data["trace.id"], "all lines must contain `trace.id`")
+			assert.NotEqual(t, "00f067aa0ba902b7", entry.Data["span.id"], "the span id must be created for this hop")
+		}
+		hook.Reset()
+	}
+
+	// Without trace context headers a new trace is started
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	h(c)
+
+	assert.Equal(t, 1, len(hook.Entries))
+	assert.Len(t, hook.LastEntry().Data["trace.id"], 32)
+}
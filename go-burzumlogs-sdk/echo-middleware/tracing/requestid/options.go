@@ -2,10 +2,13 @@ package echo_requestid
 
 import (
 	"github.com/labstack/echo/middleware"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
 )
 
 type options struct {
 	skipper middleware.Skipper
+	formats []tracecontext.Format
 }
 
 var (
@@ -32,3 +35,15 @@ func WithSkipper(s middleware.Skipper) Option {
 		o.skipper = s
 	}
 }
+
+// WithTraceContext enables the parsing of trace context headers, W3C `traceparent` and B3 by default.
+//
+// The trace context is added to the request context and logged as `trace.id` by the logging middleware.
+func WithTraceContext(formats ...tracecontext.Format) Option {
+	return func(o *options) {
+		if len(formats) == 0 {
+			formats = tracecontext.DefaultFormats
+		}
+		o.formats = formats
+	}
+}
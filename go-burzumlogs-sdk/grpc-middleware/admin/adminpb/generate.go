@@ -0,0 +1,10 @@
+// Package adminpb holds the LogAdmin service definition and its generated code.
+//
+// admin.pb.go is generated by the legacy github.com/golang/protobuf/protoc-gen-go, pinned in glide.yaml, the
+// generator writing the messages and the grpc stubs in a single file with plugins=grpc. It wraps the one of
+// google.golang.org/protobuf, whose version is the protoc-gen-go one in the file header.
+package adminpb
+
+//go:generate go build -o protoc-gen-go-legacy github.com/golang/protobuf/protoc-gen-go
+//go:generate protoc --plugin=protoc-gen-go=protoc-gen-go-legacy --go_out=plugins=grpc,paths=source_relative:. admin.proto
+//go:generate rm protoc-gen-go-legacy
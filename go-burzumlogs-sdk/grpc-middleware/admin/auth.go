@@ -0,0 +1,72 @@
+package grpc_admin
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// ServiceName is the full name of the LogAdmin service.
+const ServiceName = "burzumlogs.admin.v1.LogAdmin"
+
+// Authorizer authorizes a call to the LogAdmin service, returning who made it for the audit log.
+// The returned error is sent to the client.
+type Authorizer func(ctx context.Context, fullMethod string) (actor string, err error)
+
+type ctxActorMarker struct{}
+
+var ctxActorKey = &ctxActorMarker{}
+
+// UnaryServerInterceptor returns a new unary server interceptor authorizing the calls to the LogAdmin service
+// with authorize, the calls to other services are passed through. The LogAdmin service refuses the calls not
+// authorized by it.
+func UnaryServerInterceptor(authorize Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, "/"+ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		actor, err := authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, ctxActorKey, actor), req)
+	}
+}
+
+func actorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(ctxActorKey).(string)
+	return actor, ok
+}
+
+// BearerToken authorizes the calls with an `authorization: Bearer <token>` metadata, tokens maps each
+// accepted token to the actor name logged in the audit entries.
+func BearerToken(tokens map[string]string) Authorizer {
+	return func(ctx context.Context, fullMethod string) (string, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var auth string
+		if values := md["authorization"]; len(values) > 0 {
+			auth = values[0]
+		}
+		const prefix = "bearer "
+		if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			return "", grpc.Errorf(codes.Unauthenticated, "bearer token required")
+		}
+		given := []byte(auth[len(prefix):])
+
+		actor, found := "", false
+		for token, name := range tokens {
+			if subtle.ConstantTimeCompare(given, []byte(token)) == 1 {
+				actor, found = name, true
+			}
+		}
+		if !found {
+			return "", grpc.Errorf(codes.PermissionDenied, "invalid token")
+		}
+		return actor, nil
+	}
+}
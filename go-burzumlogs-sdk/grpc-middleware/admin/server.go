@@ -0,0 +1,146 @@
+package grpc_admin
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/admin/adminpb"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+// DefaultFlushTimeout bounds Flush when the request has no timeout.
+var DefaultFlushTimeout = 5 * time.Second
+
+// Server implements the LogAdmin service on a logrus logger and its logzum hooks.
+type Server struct {
+	adminpb.UnimplementedLogAdminServer
+
+	control *bzlogging.LevelControl
+
+	hooks []logzum.Hook
+}
+
+// NewServer returns a LogAdmin service controlling logger and hooks, it must be registered on a server
+// with UnaryServerInterceptor.
+func NewServer(logger *logrus.Logger, hooks ...logzum.Hook) *Server {
+	leveled := make([]bzlogging.LeveledHook, len(hooks))
+	for i, h := range hooks {
+		leveled[i] = h
+	}
+	return &Server{
+		control: bzlogging.NewLevelControl(logger, leveled...),
+		hooks:   hooks,
+	}
+}
+
+// Register registers the service on gs.
+func (s *Server) Register(gs *grpc.Server) {
+	adminpb.RegisterLogAdminServer(gs, s)
+}
+
+func authorized(ctx context.Context) (string, error) {
+	actor, ok := actorFromContext(ctx)
+	if !ok {
+		return "", grpc.Errorf(codes.PermissionDenied, "%s calls must be authorized by grpc_admin.UnaryServerInterceptor", ServiceName)
+	}
+	return actor, nil
+}
+
+func (s *Server) GetLevel(ctx context.Context, req *adminpb.GetLevelRequest) (*adminpb.LevelResponse, error) {
+	if _, err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	return newLevelResponse(s.control.Levels()), nil
+}
+
+func (s *Server) SetLevel(ctx context.Context, req *adminpb.SetLevelRequest) (*adminpb.LevelResponse, error) {
+	actor, err := authorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+	level, err := logrus.ParseLevel(req.GetLevel())
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	var ttl time.Duration
+	if req.GetTtl() != nil {
+		if err := req.GetTtl().CheckValid(); err != nil || req.GetTtl().AsDuration() < 0 {
+			return nil, grpc.Errorf(codes.InvalidArgument, "invalid ttl %v", req.GetTtl())
+		}
+		ttl = req.GetTtl().AsDuration()
+	}
+
+	state, err := s.control.SetLevel(actor, level, ttl)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "%v", err)
+	}
+	return newLevelResponse(state), nil
+}
+
+func (s *Server) GetHookStats(ctx context.Context, req *adminpb.GetHookStatsRequest) (*adminpb.HookStatsResponse, error) {
+	if _, err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	return s.hookStats(), nil
+}
+
+func (s *Server) Flush(ctx context.Context, req *adminpb.FlushRequest) (*adminpb.HookStatsResponse, error) {
+	if _, err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	timeout := DefaultFlushTimeout
+	if req.GetTimeout() != nil {
+		timeout = req.GetTimeout().AsDuration()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, h := range s.hooks {
+		if err := h.Flush(time.Until(deadline)); err != nil {
+			return nil, grpc.Errorf(codes.DeadlineExceeded, "%v", err)
+		}
+	}
+	return s.hookStats(), nil
+}
+
+func (s *Server) hookStats() *adminpb.HookStatsResponse {
+	res := &adminpb.HookStatsResponse{}
+	for _, h := range s.hooks {
+		stats := h.Stats()
+		res.Hooks = append(res.Hooks, &adminpb.HookStats{
+			Host:    stats.Host,
+			Level:   h.Level().String(),
+			Queued:  stats.Queued,
+			Sent:    stats.Sent,
+			Dropped: stats.Dropped,
+			Failed:  stats.Failed,
+		})
+	}
+	return res
+}
+
+func newLevelResponse(s bzlogging.LevelState) *adminpb.LevelResponse {
+	res := &adminpb.LevelResponse{
+		Logger: s.Logger.String(),
+		Hooks:  make([]string, len(s.Hooks)),
+	}
+	for i, l := range s.Hooks {
+		res.Hooks[i] = l.String()
+	}
+	if !s.RevertAt.IsZero() {
+		res.RevertAt = timestamppb.New(s.RevertAt)
+	}
+	if s.Previous != nil {
+		res.RevertTo = s.Previous.Logger.String()
+	}
+	return res
+}
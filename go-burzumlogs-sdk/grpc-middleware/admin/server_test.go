@@ -0,0 +1,166 @@
+package grpc_admin_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/admin"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/admin/adminpb"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+type adminSuite struct {
+	logger *logrus.Logger
+	audit  *test.Hook
+	hook   logzum.Hook
+	client adminpb.LogAdminClient
+	close  func()
+}
+
+func newLogzumHook(t *testing.T) logzum.Hook {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	config := logzum.DefaultConfig
+	config.Host = l.Addr().String()
+	config.MinLevel = logrus.WarnLevel
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h
+}
+
+func newAdminSuite(t *testing.T, opts ...grpc.ServerOption) *adminSuite {
+	logger, audit := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	hook := newLogzumHook(t)
+
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	grpc_admin.NewServer(logger, hook).Register(s)
+	go s.Serve(l)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return l.Dial()
+	}))
+	require.NoError(t, err)
+
+	return &adminSuite{
+		logger: logger,
+		audit:  audit,
+		hook:   hook,
+		client: adminpb.NewLogAdminClient(conn),
+		close: func() {
+			conn.Close()
+			s.Stop()
+			hook.Close()
+		},
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+var authorized = grpc.UnaryInterceptor(grpc_admin.UnaryServerInterceptor(grpc_admin.BearerToken(map[string]string{"s3cr3t": "alice"})))
+
+func TestLogAdminRequiresInterceptor(t *testing.T) {
+	s := newAdminSuite(t)
+	defer s.close()
+
+	_, err := s.client.GetLevel(withToken("s3cr3t"), &adminpb.GetLevelRequest{})
+	assert.Equal(t, codes.PermissionDenied, grpc.Code(err))
+}
+
+func TestLogAdminBearerToken(t *testing.T) {
+	s := newAdminSuite(t, authorized)
+	defer s.close()
+
+	_, err := s.client.GetLevel(context.Background(), &adminpb.GetLevelRequest{})
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	_, err = s.client.GetLevel(withToken("wrong"), &adminpb.GetLevelRequest{})
+	assert.Equal(t, codes.PermissionDenied, grpc.Code(err))
+
+	res, err := s.client.GetLevel(withToken("s3cr3t"), &adminpb.GetLevelRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "info", res.Logger)
+	assert.Equal(t, []string{"warning"}, res.Hooks)
+	assert.Nil(t, res.RevertAt)
+}
+
+func TestLogAdminSetLevel(t *testing.T) {
+	s := newAdminSuite(t, authorized)
+	defer s.close()
+
+	res, err := s.client.SetLevel(withToken("s3cr3t"), &adminpb.SetLevelRequest{
+		Level: "debug",
+		Ttl:   durationpb.New(100 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "debug", res.Logger)
+	assert.Equal(t, []string{"debug"}, res.Hooks)
+	assert.Equal(t, "info", res.RevertTo)
+	assert.NotNil(t, res.RevertAt)
+	assert.Equal(t, logrus.DebugLevel, s.logger.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, s.hook.Level())
+
+	entry := s.audit.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "alice", entry.Data["audit.actor"])
+	assert.Equal(t, "debug", entry.Data["level.new"])
+
+	assert.Eventually(t, func() bool { return s.logger.GetLevel() == logrus.InfoLevel }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, logrus.WarnLevel, s.hook.Level())
+
+	_, err = s.client.SetLevel(withToken("s3cr3t"), &adminpb.SetLevelRequest{Level: "loud"})
+	assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+}
+
+func TestLogAdminStatsAndFlush(t *testing.T) {
+	s := newAdminSuite(t, authorized)
+	defer s.close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.hook.Fire(&logrus.Entry{Message: "queued", Data: logrus.Fields{}, Level: logrus.ErrorLevel}))
+	}
+
+	res, err := s.client.Flush(withToken("s3cr3t"), &adminpb.FlushRequest{Timeout: durationpb.New(time.Second)})
+	require.NoError(t, err)
+	require.Len(t, res.Hooks, 1)
+	assert.Equal(t, uint64(3), res.Hooks[0].Sent)
+	assert.Equal(t, uint64(0), res.Hooks[0].Queued)
+
+	stats, err := s.client.GetHookStats(withToken("s3cr3t"), &adminpb.GetHookStatsRequest{})
+	require.NoError(t, err)
+	require.Len(t, stats.Hooks, 1)
+	assert.Equal(t, s.hook.Stats().Host, stats.Hooks[0].Host)
+	assert.Equal(t, "warning", stats.Hooks[0].Level)
+	assert.Equal(t, uint64(3), stats.Hooks[0].Sent)
+}
@@ -0,0 +1,39 @@
+package grpc_logzum
+
+import (
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
+)
+
+// UnaryClientInterceptor returns a new unary client interceptor that propagates the signed debug log level to the server.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(newClientDebugForCall(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that propagates the signed debug log level to the server.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(newClientDebugForCall(ctx), desc, cc, method, opts...)
+	}
+}
+
+func newClientDebugForCall(ctx context.Context) context.Context {
+	value, ok := debuglog.Extract(ctx)
+	if !ok {
+		return ctx
+	}
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md[debuglog.MetadataKey] = []string{value}
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,72 @@
+package grpc_logzum_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
+)
+
+var (
+	debugSecret = []byte("secret")
+)
+
+func TestLogrusDebugSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_logzum.Option{
+		grpc_logzum.WithDebugSecret(debugSecret),
+	}
+	b := newLogrusBaseSuite(t)
+	b.logger.Level = logrus.WarnLevel
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_logzum.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(grpc_logzum.StreamClientInterceptor()),
+	}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_logzum.StreamServerInterceptor(logrus.NewEntry(b.logger), opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger), opts...)),
+	}
+	suite.Run(t, &logrusDebugSuite{b})
+}
+
+type logrusDebugSuite struct {
+	*logrusBaseSuite
+}
+
+func (s *logrusDebugSuite) TestPing_WithSignedLevel() {
+	value := debuglog.Sign(debugSecret, logrus.DebugLevel, time.Now().Add(time.Minute))
+	ctx := debuglog.Inject(s.SimpleCtx(), value)
+
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "the call must be logged at the signed level")
+	assert.Contains(s.T(), msgs[0], `"msg": "some ping"`, "handler's message must contain user message")
+	assert.Contains(s.T(), msgs[1], `"msg": "finished unary call"`, "interceptor message must contain string")
+}
+
+func (s *logrusDebugSuite) TestPing_WithInvalidSignature() {
+	value := debuglog.Sign([]byte("other"), logrus.DebugLevel, time.Now().Add(time.Minute))
+	ctx := debuglog.Inject(s.SimpleCtx(), value)
+
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+	assert.Len(s.T(), s.getOutputJSONs(), 0, "the call must be logged at the logger level")
+}
@@ -6,6 +6,7 @@ import (
 	"google.golang.org/grpc/codes"
 
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 )
 
 var (
@@ -13,6 +14,10 @@ var (
 		levelFunc:     nil,
 		codeFunc:      DefaultErrorToCode,
 		requestIDfunc: bzlogging.DefaultRequestIDfunc,
+		extractors:    bztracing.DefaultExtractors,
+		recoveryFunc:  DefaultRecoveryHandler,
+		stackSize:     4 << 10, // 4 KB
+		flushFunc:     DefaultShouldFlush,
 	}
 )
 
@@ -28,6 +33,14 @@ type options struct {
 	levelFunc     CodeToLevel
 	codeFunc      ErrorToCode
 	requestIDfunc bzlogging.RequestIDFromContext
+	extractors    []bztracing.SpanExtractor
+	recoveryFunc  RecoveryHandlerFunc
+	stackSize     int
+	buffered      bool
+	bufferLevel   logrus.Level
+	bufferSize    int
+	flushFunc     ShouldFlush
+	debugSecret   []byte
 }
 
 func evaluateServerOpt(opts []Option) *options {
@@ -42,6 +55,9 @@ func evaluateServerOpt(opts []Option) *options {
 
 type Option func(*options)
 
+// ShouldFlush function determines whether the buffered entries of a call are flushed, given its return code.
+type ShouldFlush func(code codes.Code) bool
+
 // CodeToLevel function defines the mapping between gRPC return codes and interceptor log level.
 type CodeToLevel func(code codes.Code) logrus.Level
 
@@ -52,6 +68,13 @@ func WithRequestId(f bzlogging.RequestIDFromContext) Option {
 	}
 }
 
+// WithSpanExtractors customizes the extractors used to add the `trace.id` and `span.id` of the active span.
+func WithSpanExtractors(e ...bztracing.SpanExtractor) Option {
+	return func(o *options) {
+		o.extractors = e
+	}
+}
+
 // WithLevels customizes the function for mapping gRPC return codes and interceptor log level statements.
 func WithLevels(f CodeToLevel) Option {
 	return func(o *options) {
@@ -66,6 +89,53 @@ func WithCodes(f ErrorToCode) Option {
 	}
 }
 
+// WithRecoveryHandler customizes the function for converting recovered panics into errors.
+func WithRecoveryHandler(f RecoveryHandlerFunc) Option {
+	return func(o *options) {
+		o.recoveryFunc = f
+	}
+}
+
+// WithStackSize customizes the size of the stack trace logged by the recovery interceptors.
+func WithStackSize(size int) Option {
+	return func(o *options) {
+		o.stackSize = size
+	}
+}
+
+// WithBuffer enables the buffering of the call entries below level, which are only logged if the call fails.
+//
+// At most maxBytes are held per call, older entries are dropped first, DefaultBufferSize is used if maxBytes is zero.
+func WithBuffer(level logrus.Level, maxBytes int) Option {
+	return func(o *options) {
+		o.buffered = true
+		o.bufferLevel = level
+		o.bufferSize = maxBytes
+	}
+}
+
+// WithFlushOn customizes the function for deciding whether the buffered entries are flushed.
+func WithFlushOn(f ShouldFlush) Option {
+	return func(o *options) {
+		o.flushFunc = f
+	}
+}
+
+// WithDebugSecret enables the per-call log level of the `x-debug-log` metadata, signed with secret.
+//
+// The metadata value is created with debuglog.Sign, calls carrying a valid one are logged at its level
+// and are never buffered.
+func WithDebugSecret(secret []byte) Option {
+	return func(o *options) {
+		o.debugSecret = secret
+	}
+}
+
+// DefaultShouldFlush is the default implementation of ShouldFlush, it flushes the buffered entries of any non OK code.
+func DefaultShouldFlush(code codes.Code) bool {
+	return code != codes.OK
+}
+
 // DefaultCodeToLevel is the default implementation of gRPC return codes to log levels for server side.
 func DefaultCodeToLevel(code codes.Code) logrus.Level {
 	switch code {
@@ -0,0 +1,85 @@
+package grpc_logzum
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+
+	"golang.org/x/net/context"
+
+	"github.com/sirupsen/logrus"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+var (
+	// PanicField is the log field used to carry the recovered panic value.
+	PanicField = "panic"
+
+	// StackField is the log field used to carry the stack trace of the recovered panic.
+	StackField = "stack"
+)
+
+// RecoveryHandlerFunc function converts a recovered panic into the error returned to the client.
+type RecoveryHandlerFunc func(ctx context.Context, p interface{}) error
+
+// DefaultRecoveryHandler is the default implementation of RecoveryHandlerFunc, it returns codes.Internal.
+func DefaultRecoveryHandler(ctx context.Context, p interface{}) error {
+	return grpc.Errorf(codes.Internal, "panic: %v", p)
+}
+
+// UnaryServerRecoveryInterceptor returns a new unary server interceptor that recovers from panics.
+//
+// The panic is logged at Error level through the call-scoped logger and returned as an error, so
+// it must be installed after UnaryServerInterceptor for the completion line to be emitted.
+func UnaryServerRecoveryInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateServerOpt(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverFrom(ctx, o, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecoveryInterceptor returns a new streaming server interceptor that recovers from panics.
+//
+// The panic is logged at Error level through the call-scoped logger and returned as an error, so
+// it must be installed after StreamServerInterceptor for the completion line to be emitted.
+func StreamServerRecoveryInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateServerOpt(opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverFrom(stream.Context(), o, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func recoverFrom(ctx context.Context, o *options, fullMethodString string, r interface{}) error {
+	stack := make([]byte, o.stackSize)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	logger := bzlogging.Extract(ctx)
+	fields := logrus.Fields{
+		PanicField: fmt.Sprint(r),
+		StackField: string(stack),
+	}
+	if logger.Data["grpc.method"] == nil {
+		fields["grpc.service"] = path.Dir(fullMethodString)[1:]
+		fields["grpc.method"] = path.Base(fullMethodString)
+	}
+	if requestIDField, requestID := o.requestIDfunc(ctx); logger.Data[requestIDField] == nil {
+		fields[requestIDField] = requestID
+	}
+	logger.WithFields(fields).Error("recovered from panic")
+
+	return o.recoveryFunc(ctx, r)
+}
@@ -0,0 +1,134 @@
+package grpc_logzum_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/requestid"
+)
+
+type panickingPingService struct {
+	pb_testproto.TestServiceServer
+}
+
+func (s *panickingPingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	panic("boom")
+}
+
+func (s *panickingPingService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
+	panic("boom")
+}
+
+func TestLogrusRecoverySuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_logzum.Option{
+		grpc_logzum.WithRequestId(func(ctx context.Context) (string, interface{}) {
+			return bzlogging.RequestIDTorequestIDField("foo")
+		}),
+	}
+	b := newLogrusBaseSuite(t)
+	b.InterceptorTestSuite.TestService = &panickingPingService{&grpc_testing.TestPingService{T: t}}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_requestid.StreamServerInterceptor(),
+			grpc_logzum.StreamServerInterceptor(logrus.NewEntry(b.logger), opts...),
+			grpc_logzum.StreamServerRecoveryInterceptor(opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_requestid.UnaryServerInterceptor(),
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger), opts...),
+			grpc_logzum.UnaryServerRecoveryInterceptor(opts...)),
+	}
+	suite.Run(t, &logrusRecoverySuite{b})
+}
+
+type logrusRecoverySuite struct {
+	*logrusBaseSuite
+}
+
+func (s *logrusRecoverySuite) TestPing_RecoversPanic() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.Error(s.T(), err, "the panic must be returned as an error")
+	assert.Equal(s.T(), codes.Internal, grpc.Code(err))
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "the panic and the interceptor log messages must be printed")
+	for _, m := range msgs {
+		assert.Contains(s.T(), m, `"grpc.service": "mwitkow.testproto.TestService"`, "all lines must contain service name")
+		assert.Contains(s.T(), m, `"grpc.method": "Ping"`, "all lines must contain method name")
+		assert.Contains(s.T(), m, `"requestID": "foo"`, "all lines must contain `requestID`")
+		assert.Contains(s.T(), m, `"level": "error"`, "all lines must be logged on error level")
+	}
+	assert.Contains(s.T(), msgs[0], `"msg": "recovered from panic"`, "the panic must be logged")
+	assert.Contains(s.T(), msgs[0], `"panic": "boom"`, "the panic value must be logged")
+	assert.Contains(s.T(), msgs[0], `"stack": "goroutine`, "the stack trace must be logged")
+	assert.Contains(s.T(), msgs[1], `"msg": "finished unary call"`, "interceptor message must contain string")
+	assert.Contains(s.T(), msgs[1], `"grpc.code": "Internal"`, "the panic must be logged as Internal")
+}
+
+func (s *logrusRecoverySuite) TestPingList_RecoversPanic() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	assert.Equal(s.T(), codes.Internal, grpc.Code(err))
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "the panic and the interceptor log messages must be printed")
+	assert.Contains(s.T(), msgs[0], `"grpc.method": "PingList"`, "all lines must contain method name")
+	assert.Contains(s.T(), msgs[0], `"msg": "recovered from panic"`, "the panic must be logged")
+	assert.Contains(s.T(), msgs[1], `"msg": "finished streaming call"`, "interceptor message must contain string")
+}
+
+func TestLogrusRecoveryHandlerSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_logzum.Option{
+		grpc_logzum.WithRecoveryHandler(func(ctx context.Context, p interface{}) error {
+			return grpc.Errorf(codes.Unavailable, "try again")
+		}),
+	}
+	b := newLogrusBaseSuite(t)
+	b.InterceptorTestSuite.TestService = &panickingPingService{&grpc_testing.TestPingService{T: t}}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpc_logzum.UnaryServerRecoveryInterceptor(opts...)),
+	}
+	suite.Run(t, &logrusRecoveryHandlerSuite{b})
+}
+
+type logrusRecoveryHandlerSuite struct {
+	*logrusBaseSuite
+}
+
+func (s *logrusRecoveryHandlerSuite) TestPing_CustomError() {
+	bzlogging.L = logrus.NewEntry(s.logger)
+	defer func() { bzlogging.L = logrus.NewEntry(logrus.StandardLogger()) }()
+
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.Equal(s.T(), codes.Unavailable, grpc.Code(err), "the recovery handler must customize the error")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the panic log message is printed")
+	assert.Contains(s.T(), msgs[0], `"grpc.service": "mwitkow.testproto.TestService"`, "the service name must be logged without the logging interceptor")
+	assert.Contains(s.T(), msgs[0], `"grpc.method": "Ping"`, "the method name must be logged without the logging interceptor")
+}
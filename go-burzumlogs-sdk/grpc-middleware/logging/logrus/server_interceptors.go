@@ -10,8 +10,12 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/debuglog"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 )
 
 var (
@@ -23,7 +27,8 @@ var (
 func UnaryServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.UnaryServerInterceptor {
 	o := evaluateServerOpt(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		newCtx := newLoggerForCall(ctx, o, entry, info.FullMethod)
+		ctx, buffer, callEntry := newEntryForCall(ctx, o, entry)
+		newCtx := newLoggerForCall(ctx, o, callEntry, info.FullMethod)
 
 		startTime := time.Now()
 		resp, err := handler(newCtx, req)
@@ -39,6 +44,7 @@ func UnaryServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.UnaryServe
 		if err != nil {
 			fields[logrus.ErrorKey] = err
 		}
+		closeBuffer(o, buffer, code, fields)
 		levelLogf(
 			bzlogging.Extract(newCtx).WithFields(fields), // re-extract logger from newCtx, as it may have extra fields that changed in the holder.
 			level,
@@ -51,7 +57,8 @@ func UnaryServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.UnaryServe
 func StreamServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.StreamServerInterceptor {
 	o := evaluateServerOpt(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		newCtx := newLoggerForCall(stream.Context(), o, entry, info.FullMethod)
+		ctx, buffer, callEntry := newEntryForCall(stream.Context(), o, entry)
+		newCtx := newLoggerForCall(ctx, o, callEntry, info.FullMethod)
 		wrapped := grpc_middleware.WrapServerStream(stream)
 		wrapped.WrappedContext = newCtx
 
@@ -68,6 +75,7 @@ func StreamServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.StreamSer
 		if err != nil {
 			fields[logrus.ErrorKey] = err
 		}
+		closeBuffer(o, buffer, code, fields)
 		levelLogf(
 			bzlogging.Extract(newCtx).WithFields(fields), // re-extract logger from newCtx, as it may have extra fields that changed in the holder.
 			level,
@@ -76,6 +84,37 @@ func StreamServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.StreamSer
 	}
 }
 
+// newEntryForCall returns the entry the call must log with, lowered to the signed debug level or buffered if enabled.
+func newEntryForCall(ctx context.Context, o *options, entry *logrus.Entry) (context.Context, *bzlogging.Buffer, *logrus.Entry) {
+	var debugValue string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[debuglog.MetadataKey]) != 0 {
+		debugValue = md[debuglog.MetadataKey][0]
+		ctx = debuglog.Inject(ctx, debugValue)
+	}
+	if level, ok := debuglog.Verify(o.debugSecret, debugValue, time.Now()); ok {
+		return ctx, nil, bzlogging.WithLevel(entry, level)
+	}
+	if !o.buffered {
+		return ctx, nil, entry
+	}
+	buffer := bzlogging.NewBuffer(entry, o.bufferLevel, o.bufferSize)
+	return ctx, buffer, buffer.Entry()
+}
+
+// closeBuffer flushes or discards the call buffer before the completion line is logged.
+func closeBuffer(o *options, buffer *bzlogging.Buffer, code codes.Code, fields logrus.Fields) {
+	if buffer == nil {
+		return
+	}
+	if !o.flushFunc(code) {
+		buffer.Discard()
+		return
+	}
+	if dropped := buffer.Flush(); dropped > 0 {
+		fields["buffer.dropped"] = dropped
+	}
+}
+
 func timeDiff(then time.Time) time.Duration {
 	return time.Now().Sub(then)
 }
@@ -101,13 +140,16 @@ func newLoggerForCall(ctx context.Context, o *options, entry *logrus.Entry, full
 	requestIDField, requestID := o.requestIDfunc(ctx)
 	service := path.Dir(fullMethodString)[1:]
 	method := path.Base(fullMethodString)
-	callLog := entry.WithFields(
-		logrus.Fields{
-			KindField:      "server",
-			"grpc.service": service,
-			"grpc.method":  method,
-			requestIDField: requestID,
-		})
+	fields := logrus.Fields{
+		KindField:      "server",
+		"grpc.service": service,
+		"grpc.method":  method,
+		requestIDField: requestID,
+	}
+	for k, v := range bztracing.Fields(ctx, o.extractors) {
+		fields[k] = v
+	}
+	callLog := entry.WithFields(fields)
 
 	return bzlogging.Inject(ctx, callLog)
 }
@@ -18,9 +18,12 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracing"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/requestid"
 )
@@ -118,3 +121,86 @@ func (s *logrusServerSuite) TestPingList_WithRequestID() {
 	assert.Contains(s.T(), msgs[1], `"grpc.duration":`, "interceptor log statement should contain execution time")
 	assert.Contains(s.T(), msgs[1], `"grpc.duration_human":`, "interceptor log statement should contain execution time")
 }
+
+func TestLogrusServerSpanSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	tracer := mocktracer.New()
+	opts := []grpc_logzum.Option{
+		grpc_logzum.WithSpanExtractors(bztracing.MockExtractor),
+	}
+	b := newLogrusBaseSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithUnaryServerChain(
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				span := tracer.StartSpan(info.FullMethod)
+				defer span.Finish()
+				return handler(opentracing.ContextWithSpan(ctx, span), req)
+			},
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger), opts...)),
+	}
+	suite.Run(t, &logrusServerSpanSuite{b})
+}
+
+type logrusServerSpanSuite struct {
+	*logrusBaseSuite
+}
+
+func (s *logrusServerSpanSuite) TestPing_WithSpan() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+	msgs := s.getOutputJSONs()
+	assert.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		assert.Contains(s.T(), m, `"trace.id": "`, "all lines must contain `trace.id`")
+		assert.Contains(s.T(), m, `"span.id": "`, "all lines must contain `span.id`")
+	}
+}
+
+func TestLogrusServerBufferSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_logzum.Option{
+		grpc_logzum.WithBuffer(logrus.WarnLevel, 0),
+	}
+	b := newLogrusBaseSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_logzum.StreamServerInterceptor(logrus.NewEntry(b.logger), opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger), opts...)),
+	}
+	suite.Run(t, &logrusServerBufferSuite{b})
+}
+
+type logrusServerBufferSuite struct {
+	*logrusBaseSuite
+}
+
+func (s *logrusServerBufferSuite) TestPing_DiscardsBuffer() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the interceptor log message is printed on success")
+	assert.Contains(s.T(), msgs[0], `"msg": "finished unary call"`, "interceptor message must contain string")
+}
+
+func (s *logrusServerBufferSuite) TestPingList_FlushesBuffer() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)})
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	require.Error(s.T(), err, "the call must fail")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "the buffered log message must be flushed on failure")
+	for _, m := range msgs {
+		assert.Contains(s.T(), m, `"grpc.method": "PingList"`, "all lines must contain method name")
+	}
+	assert.Contains(s.T(), msgs[0], `"msg": "some pinglist"`, "handler's message must contain user message")
+	assert.Contains(s.T(), msgs[0], `"level": "info"`, "the buffered message must keep its level")
+	assert.Contains(s.T(), msgs[1], `"msg": "finished streaming call"`, "interceptor message must contain string")
+}
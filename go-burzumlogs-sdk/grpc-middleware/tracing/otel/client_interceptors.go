@@ -0,0 +1,95 @@
+package grpc_otel
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UnaryClientInterceptor returns a new unary client interceptor that creates an OpenTelemetry span for the call
+// and propagates it to the server.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateOpt(opts)
+	tracer := o.tracer()
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		newCtx, span := newClientSpanForCall(ctx, tracer, o, method)
+		defer span.End()
+
+		err := invoker(newCtx, method, req, reply, cc, callOpts...)
+		finishSpan(span, grpc.Code(err), err, false)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that creates an OpenTelemetry span for the call
+// and propagates it to the server.
+//
+// The span is finished when the stream ends, which requires the caller to drain it.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := evaluateOpt(opts)
+	tracer := o.tracer()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		newCtx, span := newClientSpanForCall(ctx, tracer, o, method)
+
+		cs, err := streamer(newCtx, desc, cc, method, callOpts...)
+		if err != nil {
+			finishSpan(span, grpc.Code(err), err, false)
+			span.End()
+			return nil, err
+		}
+		return &tracedClientStream{ClientStream: cs, desc: desc, span: span}, nil
+	}
+}
+
+func newClientSpanForCall(ctx context.Context, tracer trace.Tracer, o *options, fullMethodString string) (context.Context, trace.Span) {
+	newCtx, span := tracer.Start(
+		ctx,
+		spanName(fullMethodString),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(spanAttributes(ctx, o, fullMethodString)...),
+	)
+
+	md, ok := metadata.FromOutgoingContext(newCtx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	o.propagator.Inject(newCtx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(newCtx, md), span
+}
+
+// tracedClientStream finishes the span once the server closes the stream.
+type tracedClientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	span trace.Span
+	once sync.Once
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *tracedClientStream) finish(err error) {
+	s.once.Do(func() {
+		finishSpan(s.span, grpc.Code(err), err, false)
+		s.span.End()
+	})
+}
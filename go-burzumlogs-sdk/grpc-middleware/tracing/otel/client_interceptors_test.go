@@ -0,0 +1,69 @@
+package grpc_otel_test
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/otel"
+)
+
+func TestOtelClientSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	b := newOtelBaseSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_otel.UnaryClientInterceptor(grpc_otel.WithTracerProvider(b.tracerProvider))),
+		grpc.WithStreamInterceptor(grpc_otel.StreamClientInterceptor(grpc_otel.WithTracerProvider(b.tracerProvider))),
+	}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpc_otel.UnaryServerInterceptor(grpc_otel.WithTracerProvider(b.tracerProvider))),
+		grpc.StreamInterceptor(grpc_otel.StreamServerInterceptor(grpc_otel.WithTracerProvider(b.tracerProvider))),
+	}
+	suite.Run(t, &otelClientSuite{b})
+}
+
+type otelClientSuite struct {
+	*otelBaseSuite
+}
+
+func (s *otelClientSuite) TestPing_PropagatesSpan() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+
+	spans := s.exporter.GetSpans()
+	require.Len(s.T(), spans, 2, "client and server spans should be recorded")
+	server, client := spans[0], spans[1]
+	assert.Equal(s.T(), trace.SpanKindServer, server.SpanKind)
+	assert.Equal(s.T(), trace.SpanKindClient, client.SpanKind)
+	assert.Equal(s.T(), client.SpanContext.TraceID(), server.SpanContext.TraceID(), "both spans must share the trace")
+	assert.Equal(s.T(), client.SpanContext.SpanID(), server.Parent.SpanID(), "the server span must be a child of the client span")
+}
+
+func (s *otelClientSuite) TestPingList_FinishesSpanOnEOF() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	spans := s.exporter.GetSpans()
+	require.Len(s.T(), spans, 2, "client and server spans should be recorded")
+	assert.Equal(s.T(), trace.SpanKindClient, spans[1].SpanKind)
+	assert.Equal(s.T(), spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID(), "the server span must be a child of the client span")
+}
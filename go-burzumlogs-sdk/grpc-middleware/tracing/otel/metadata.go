@@ -0,0 +1,75 @@
+package grpc_otel
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// metadataCarrier adapts metadata.MD to the propagation.TextMapCarrier interface.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := c[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	c[strings.ToLower(key)] = []string{value}
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func spanAttributes(ctx context.Context, o *options, fullMethodString string) []attribute.KeyValue {
+	requestIDField, requestID := o.requestIDfunc(ctx)
+	return []attribute.KeyValue{
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(path.Dir(fullMethodString)[1:]),
+		semconv.RPCMethod(path.Base(fullMethodString)),
+		attribute.String(requestIDField, fmt.Sprint(requestID)),
+	}
+}
+
+func spanName(fullMethodString string) string {
+	return strings.TrimPrefix(fullMethodString, "/")
+}
+
+// finishSpan records the call outcome, server spans only flag codes that indicate a server fault as errors.
+func finishSpan(span trace.Span, code codes.Code, err error, server bool) {
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	if !server || isServerError(code) {
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
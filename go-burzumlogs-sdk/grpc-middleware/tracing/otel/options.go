@@ -0,0 +1,84 @@
+package grpc_otel
+
+import (
+	"golang.org/x/net/context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+)
+
+const (
+	// InstrumentationName is the name of the tracer created by the interceptors.
+	InstrumentationName = "github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/otel"
+)
+
+var (
+	defaultOptions = &options{
+		skipper:       DefaultSkipper,
+		propagator:    propagation.TraceContext{},
+		requestIDfunc: bzlogging.DefaultRequestIDfunc,
+	}
+)
+
+// Skipper decides whether the call to fullMethod is served without a span, as health checks.
+type Skipper func(ctx context.Context, fullMethod string) bool
+
+// DefaultSkipper creates a span for every call.
+func DefaultSkipper(ctx context.Context, fullMethod string) bool {
+	return false
+}
+
+type options struct {
+	skipper        Skipper
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	requestIDfunc  bzlogging.RequestIDFromContext
+}
+
+func evaluateOpt(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.tracerProvider = otel.GetTracerProvider()
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+func (o *options) tracer() trace.Tracer {
+	return o.tracerProvider.Tracer(InstrumentationName, trace.WithSchemaURL(semconv.SchemaURL))
+}
+
+type Option func(*options)
+
+// WithSkipper customizes the function for skip the calls, on the server interceptors.
+func WithSkipper(s Skipper) Option {
+	return func(o *options) {
+		o.skipper = s
+	}
+}
+
+// WithTracerProvider customizes the provider used to create the tracer, the global one is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator customizes the propagator used to carry the span context, W3C `traceparent` is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+// WithRequestId customizes the function for get the request id.
+func WithRequestId(f bzlogging.RequestIDFromContext) Option {
+	return func(o *options) {
+		o.requestIDfunc = f
+	}
+}
@@ -0,0 +1,62 @@
+package grpc_otel
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UnaryServerInterceptor returns a new unary server interceptor that creates an OpenTelemetry span for the call.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOpt(opts)
+	tracer := o.tracer()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if o.skipper(ctx, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		newCtx, span := newServerSpanForCall(ctx, tracer, o, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(newCtx, req)
+		finishSpan(span, grpc.Code(err), err, true)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor that creates an OpenTelemetry span for the call.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOpt(opts)
+	tracer := o.tracer()
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if o.skipper(stream.Context(), info.FullMethod) {
+			return handler(srv, stream)
+		}
+		newCtx, span := newServerSpanForCall(stream.Context(), tracer, o, info.FullMethod)
+		defer span.End()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+
+		err := handler(srv, wrapped)
+		finishSpan(span, grpc.Code(err), err, true)
+		return err
+	}
+}
+
+func newServerSpanForCall(ctx context.Context, tracer trace.Tracer, o *options, fullMethodString string) (context.Context, trace.Span) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+	parentCtx := o.propagator.Extract(ctx, metadataCarrier(md))
+	return tracer.Start(
+		parentCtx,
+		spanName(fullMethodString),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(spanAttributes(ctx, o, fullMethodString)...),
+	)
+}
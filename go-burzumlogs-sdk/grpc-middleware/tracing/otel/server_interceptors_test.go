@@ -0,0 +1,158 @@
+package grpc_otel_test
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/otel"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/tracing/requestid"
+)
+
+func TestOtelServerSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	b := newOtelBaseSuite(t)
+	opts := []grpc_otel.Option{
+		grpc_otel.WithTracerProvider(b.tracerProvider),
+		grpc_otel.WithRequestId(func(ctx context.Context) (string, interface{}) {
+			return bzlogging.RequestIDTorequestIDField(requestid.Extract(ctx))
+		}),
+	}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_requestid.StreamServerInterceptor(),
+			grpc_otel.StreamServerInterceptor(opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_requestid.UnaryServerInterceptor(),
+			grpc_otel.UnaryServerInterceptor(opts...)),
+	}
+	suite.Run(t, &otelServerSuite{b})
+}
+
+type otelServerSuite struct {
+	*otelBaseSuite
+}
+
+func (s *otelServerSuite) TestPing_WithTraceparent() {
+	md := metadata.Pairs(
+		requestid.DefaultXRequestIDKey, "foo",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	)
+	ctx := metadata.NewOutgoingContext(s.SimpleCtx(), md)
+
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an on a successful call")
+
+	spans := s.exporter.GetSpans()
+	require.Len(s.T(), spans, 1, "one span should be recorded")
+	span := spans[0]
+	attrs := attributes(span)
+	assert.Equal(s.T(), "mwitkow.testproto.TestService/Ping", span.Name)
+	assert.Equal(s.T(), trace.SpanKindServer, span.SpanKind)
+	assert.Equal(s.T(), "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), "the span must continue the incoming trace")
+	assert.Equal(s.T(), "00f067aa0ba902b7", span.Parent.SpanID().String(), "the span must be a child of the incoming span")
+	assert.Equal(s.T(), "grpc", attrs["rpc.system"].AsString())
+	assert.Equal(s.T(), "mwitkow.testproto.TestService", attrs["rpc.service"].AsString(), "the span must contain service name")
+	assert.Equal(s.T(), "Ping", attrs["rpc.method"].AsString(), "the span must contain method name")
+	assert.Equal(s.T(), "foo", attrs["requestID"].AsString(), "the span must contain `requestID`")
+	assert.Equal(s.T(), int64(0), attrs["rpc.grpc.status_code"].AsInt64())
+}
+
+func (s *otelServerSuite) TestPingError_SetsStatus() {
+	for _, tcase := range []struct {
+		code   grpccodes.Code
+		status codes.Code
+	}{
+		{code: grpccodes.Internal, status: codes.Error},
+		{code: grpccodes.NotFound, status: codes.Unset},
+	} {
+		s.exporter.Reset()
+		_, err := s.Client.PingError(
+			s.SimpleCtx(),
+			&pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(tcase.code)})
+		assert.Error(s.T(), err, "each call here must return an error")
+
+		spans := s.exporter.GetSpans()
+		require.Len(s.T(), spans, 1, "one span should be recorded")
+		assert.Equal(s.T(), int64(tcase.code), attributes(spans[0])["rpc.grpc.status_code"].AsInt64())
+		assert.Equal(s.T(), tcase.status, spans[0].Status.Code, "only server faults must set the span status")
+	}
+}
+
+func (s *otelServerSuite) TestPingList() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	spans := s.exporter.GetSpans()
+	require.Len(s.T(), spans, 1, "one span should be recorded")
+	assert.Equal(s.T(), "mwitkow.testproto.TestService/PingList", spans[0].Name)
+	assert.False(s.T(), spans[0].Parent.IsValid(), "the span must be a root span without `traceparent`")
+}
+
+func TestOtelServerSkipperSuite(t *testing.T) {
+	b := newOtelBaseSuite(t)
+	opts := []grpc_otel.Option{
+		grpc_otel.WithTracerProvider(b.tracerProvider),
+		grpc_otel.WithSkipper(func(ctx context.Context, fullMethod string) bool {
+			return strings.HasSuffix(fullMethod, "/Ping") || strings.HasSuffix(fullMethod, "/PingList")
+		}),
+	}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc.StreamInterceptor(grpc_otel.StreamServerInterceptor(opts...)),
+		grpc.UnaryInterceptor(grpc_otel.UnaryServerInterceptor(opts...)),
+	}
+	suite.Run(t, &otelSkipperSuite{b})
+}
+
+type otelSkipperSuite struct {
+	*otelBaseSuite
+}
+
+func (s *otelSkipperSuite) TestSkipped() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "the skipped calls must still be served")
+
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	assert.Empty(s.T(), s.exporter.GetSpans(), "the skipped calls must not create spans")
+
+	_, err = s.Client.PingEmpty(s.SimpleCtx(), &pb_testproto.Empty{})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), s.exporter.GetSpans(), 1, "the other calls must create spans")
+}
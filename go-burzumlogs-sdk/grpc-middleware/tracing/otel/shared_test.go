@@ -0,0 +1,45 @@
+package grpc_otel_test
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+)
+
+var (
+	goodPing = &pb_testproto.PingRequest{Value: "something", SleepTimeMs: 9999}
+)
+
+type otelBaseSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	exporter       *tracetest.InMemoryExporter
+	tracerProvider *sdktrace.TracerProvider
+}
+
+func newOtelBaseSuite(t *testing.T) *otelBaseSuite {
+	exporter := tracetest.NewInMemoryExporter()
+	return &otelBaseSuite{
+		exporter:       exporter,
+		tracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &grpc_testing.TestPingService{T: t},
+		},
+	}
+}
+
+func (s *otelBaseSuite) SetupTest() {
+	s.exporter.Reset()
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
@@ -1,35 +1,63 @@
 package grpc_requestid
 
 import (
+	"strings"
+
 	"golang.org/x/net/context"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+)
+
+var (
+	// b3Keys are dropped from the forwarded metadata when the trace context is regenerated.
+	b3Keys = []string{
+		tracecontext.B3Header,
+		tracecontext.B3TraceIDHeader,
+		tracecontext.B3SpanIDHeader,
+		tracecontext.B3ParentSpanIDHeader,
+		tracecontext.B3SampledHeader,
+		tracecontext.B3FlagsHeader,
+	}
 )
 
 // UnaryClientInterceptor returns a new unary client interceptor that adds request id  to the context.
-func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateOpt(opts)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		ctx = newClientRequestIDForCall(ctx)
+		ctx = newClientRequestIDForCall(ctx, o)
 
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 }
 
 // StreamServerInterceptor returns a new streaming client interceptor that adds request id  to the context.
-func StreamClientInterceptor() grpc.StreamClientInterceptor {
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := evaluateOpt(opts)
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		ctx = newClientRequestIDForCall(ctx)
+		ctx = newClientRequestIDForCall(ctx, o)
 		return streamer(ctx, desc, cc, method, opts...)
 	}
 }
 
-func newClientRequestIDForCall(ctx context.Context) context.Context {
+func newClientRequestIDForCall(ctx context.Context, o *options) context.Context {
 	id := requestid.Extract(ctx)
 	ctx = requestid.Inject(ctx, id)
 	md := toMD(ctx, id)
+	if o.formats != nil {
+		tc, ok := tracecontext.Extract(ctx)
+		if !ok {
+			tc = tracecontext.New()
+		}
+		for _, k := range b3Keys {
+			delete(md, strings.ToLower(k))
+		}
+		// the span id of this hop is sent as the parent of the next one.
+		md[tracecontext.TraceparentHeader] = []string{tc.Traceparent()}
+	}
 	return metadata.NewOutgoingContext(ctx, md)
 }
 
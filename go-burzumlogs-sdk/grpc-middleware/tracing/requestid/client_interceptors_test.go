@@ -19,6 +19,7 @@ import (
 
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/grpc-middleware/logging/logrus"
 )
 
@@ -100,3 +101,55 @@ func (s *requestIDClientSuite) TestPing() {
 	assert.Contains(s.T(), msgs[1], `"grpc.duration":`, "interceptor log statement should contain execution time")
 	assert.Contains(s.T(), msgs[1], `"grpc.duration_human":`, "interceptor log statement should contain execution time")
 }
+
+func TestTraceContextClientSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+
+	b := newRequestIDBaseSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_requestid.UnaryClientInterceptor(grpc_requestid.WithTraceContext())),
+		grpc.WithStreamInterceptor(grpc_requestid.StreamClientInterceptor(grpc_requestid.WithTraceContext())),
+	}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_requestid.StreamServerInterceptor(grpc_requestid.WithTraceContext()),
+			grpc_logzum.StreamServerInterceptor(logrus.NewEntry(b.logger))),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_requestid.UnaryServerInterceptor(grpc_requestid.WithTraceContext()),
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger))),
+	}
+	suite.Run(t, &traceContextClientSuite{b})
+}
+
+type traceContextClientSuite struct {
+	*requestIDBaseSuite
+}
+
+func (s *traceContextClientSuite) TestPing_PropagatesTraceContext() {
+	tc := tracecontext.New()
+	ctx := tracecontext.Inject(s.SimpleCtx(), tc)
+
+	_, err := s.Client.Ping(ctx, goodPing)
+	assert.NoError(s.T(), err, "there must be not be an on a successful call")
+	msgs := s.getOutputJSONs()
+
+	assert.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		assert.Contains(s.T(), m, `"trace.id": "`+tc.TraceID+`"`, "all lines must contain the caller `trace.id`")
+		assert.NotContains(s.T(), m, `"span.id": "`+tc.SpanID+`"`, "the server must create its own span id")
+	}
+}
+
+func (s *traceContextClientSuite) TestPing_StartsTraceContext() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.NoError(s.T(), err, "there must be not be an on a successful call")
+	msgs := s.getOutputJSONs()
+
+	assert.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		assert.Contains(s.T(), m, `"trace.id": "`, "all lines must contain `trace.id`")
+	}
+}
@@ -0,0 +1,52 @@
+package grpc_requestid
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+)
+
+type options struct {
+	formats []tracecontext.Format
+}
+
+var (
+	defaultOptions = &options{}
+)
+
+func evaluateOpt(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithTraceContext enables the propagation of trace context metadata, W3C `traceparent` and B3 by default.
+//
+// Server interceptors add the incoming trace context to the context, it is logged as `trace.id` by the logging
+// interceptors. Client interceptors send a `traceparent` for the next hop.
+func WithTraceContext(formats ...tracecontext.Format) Option {
+	return func(o *options) {
+		if len(formats) == 0 {
+			formats = tracecontext.DefaultFormats
+		}
+		o.formats = formats
+	}
+}
+
+// mdGetter returns a lookup function for md, metadata keys are always lowercase.
+func mdGetter(md metadata.MD) func(key string) string {
+	return func(key string) string {
+		values := md[strings.ToLower(key)]
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+}
@@ -8,22 +8,25 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/requestid"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
 	"google.golang.org/grpc/metadata"
 )
 
 // UnaryServerInterceptor returns a new unary server interceptors that adds request id to the context.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOpt(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		newCtx := newRequestIDForCall(ctx)
+		newCtx := newRequestIDForCall(ctx, o)
 		resp, err := handler(newCtx, req)
 		return resp, err
 	}
 }
 
 // StreamServerInterceptor returns a new streaming server interceptor that adds request id  to the context.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOpt(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		newCtx := newRequestIDForCall(stream.Context())
+		newCtx := newRequestIDForCall(stream.Context(), o)
 		wrapped := grpc_middleware.WrapServerStream(stream)
 		wrapped.WrappedContext = newCtx
 		err := handler(srv, wrapped)
@@ -32,7 +35,7 @@ func StreamServerInterceptor() grpc.StreamServerInterceptor {
 	}
 }
 
-func newRequestIDForCall(ctx context.Context) context.Context {
+func newRequestIDForCall(ctx context.Context, o *options) context.Context {
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	var id string
@@ -50,5 +53,9 @@ func newRequestIDForCall(ctx context.Context) context.Context {
 		id = requestid.NewRequestID()
 	}
 
+	if o.formats != nil {
+		ctx = tracecontext.Inject(ctx, tracecontext.Continue(mdGetter(md), o.formats))
+	}
+
 	return requestid.Inject(ctx, id)
 }
@@ -80,3 +80,42 @@ func (s *requestIDServerSuite) TestPingList_WithRequestIDFromMetadata() {
 	assert.Contains(s.T(), msgs[1], `"grpc.duration":`, "interceptor log statement should contain execution time")
 	assert.Contains(s.T(), msgs[1], `"grpc.duration_human":`, "interceptor log statement should contain execution time")
 }
+
+func TestTraceContextServerSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	b := newRequestIDBaseSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_requestid.StreamServerInterceptor(grpc_requestid.WithTraceContext()),
+			grpc_logzum.StreamServerInterceptor(logrus.NewEntry(b.logger))),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_requestid.UnaryServerInterceptor(grpc_requestid.WithTraceContext()),
+			grpc_logzum.UnaryServerInterceptor(logrus.NewEntry(b.logger))),
+	}
+	suite.Run(t, &traceContextServerSuite{b})
+}
+
+type traceContextServerSuite struct {
+	*requestIDBaseSuite
+}
+
+func (s *traceContextServerSuite) TestPing_WithTraceContextFromMetadata() {
+	for _, md := range []metadata.MD{
+		metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+		metadata.Pairs("x-b3-traceid", "4bf92f3577b34da6a3ce929d0e0e4736", "x-b3-spanid", "00f067aa0ba902b7"),
+	} {
+		ctx := metadata.NewOutgoingContext(s.SimpleCtx(), md)
+
+		_, err := s.Client.Ping(ctx, goodPing)
+		require.NoError(s.T(), err, "there must be not be an on a successful call")
+		msgs := s.getOutputJSONs()
+		assert.Len(s.T(), msgs, 2, "two log statements should be logged")
+		for _, m := range msgs {
+			assert.Contains(s.T(), m, `"trace.id": "4bf92f3577b34da6a3ce929d0e0e4736"`, "all lines must contain `trace.id`")
+			assert.Contains(s.T(), m, `"span.id": "`, "all lines must contain `span.id`")
+		}
+	}
+}
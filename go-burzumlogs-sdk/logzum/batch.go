@@ -0,0 +1,268 @@
+package logzum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sink sends the entries of a pipeline in place of the formatter and the transport.
+type sink interface {
+	add(entry *logrus.Entry)
+	// flush sends the queued entries without waiting for the batch timeout.
+	flush()
+	// close sends the queued entries and releases the sink.
+	close() error
+	// endpoint is reported as the host of the stats.
+	endpoint() string
+}
+
+// batchConfig is the batching of the sinks sending many entries per request, taken from their configs.
+type batchConfig struct {
+	size      int
+	timeout   time.Duration
+	queueSize int
+}
+
+func (c batchConfig) withDefaults() batchConfig {
+	if c.size == 0 {
+		c.size = 512
+	}
+	if c.timeout == 0 {
+		c.timeout = time.Second
+	}
+	if c.queueSize == 0 {
+		c.queueSize = 2048
+	}
+	return c
+}
+
+// RetryConfig is the exponential backoff of the requests failed with a retryable error. The delay asked by the
+// server is respected.
+type RetryConfig struct {
+	// Disabled gives up the entries on the first error.
+	Disabled bool `yaml:"disabled"`
+	// InitialInterval is the delay before the first retry, 5s by default.
+	InitialInterval time.Duration `yaml:"initial-interval"`
+	// MaxInterval is the maximum delay between retries, 30s by default.
+	MaxInterval time.Duration `yaml:"max-interval"`
+	// MaxElapsedTime is the maximum time spent on a batch, retries included, 1m by default.
+	MaxElapsedTime time.Duration `yaml:"max-elapsed-time"`
+}
+
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.InitialInterval == 0 {
+		c.InitialInterval = 5 * time.Second
+	}
+	if c.MaxInterval == 0 {
+		c.MaxInterval = 30 * time.Second
+	}
+	if c.MaxElapsedTime == 0 {
+		c.MaxElapsedTime = time.Minute
+	}
+	return c
+}
+
+// batchProblems reports the invalid batch and retry values of the sink name.
+func batchProblems(name string, timeout time.Duration, batch batchConfig, retry RetryConfig) []string {
+	var problems []string
+	if timeout < 0 || batch.size < 0 || batch.timeout < 0 || batch.queueSize < 0 {
+		problems = append(problems, fmt.Sprintf("%s values must not be negative", name))
+	}
+	if retry.InitialInterval < 0 || retry.MaxInterval < 0 || retry.MaxElapsedTime < 0 {
+		problems = append(problems, fmt.Sprintf("%s retry values must not be negative", name))
+	}
+	return problems
+}
+
+// retryableError is a request error worth another attempt, after throttle when the server asked for a delay.
+// On requests accepted in part, pending are the entries to send again and rejected the number of entries given up.
+type retryableError struct {
+	err      error
+	throttle time.Duration
+	pending  []*logrus.Entry
+	rejected int
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// batcher queues the entries of a sink and sends them in batches with retries, counting them on the hook counters.
+type batcher struct {
+	name     string
+	batch    batchConfig
+	retry    RetryConfig
+	timeout  time.Duration
+	counters *counters
+
+	// send returns the number of entries rejected by the server
+	send func(ctx context.Context, entries []*logrus.Entry) (int, error)
+
+	// mu guards closed, so the entries are not sent to a closed channel
+	mu     sync.RWMutex
+	closed bool
+	entryC chan *logrus.Entry
+	flushC chan struct{}
+	done   chan struct{}
+}
+
+// newBatcher starts a batcher, with timeout as the limit of each attempt.
+func newBatcher(name string, batch batchConfig, retry RetryConfig, timeout time.Duration, counters *counters,
+	send func(ctx context.Context, entries []*logrus.Entry) (int, error)) *batcher {
+	b := &batcher{
+		name:     name,
+		batch:    batch.withDefaults(),
+		retry:    retry.withDefaults(),
+		timeout:  timeout,
+		counters: counters,
+		send:     send,
+		flushC:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if b.timeout == 0 {
+		b.timeout = 10 * time.Second
+	}
+	b.entryC = make(chan *logrus.Entry, b.batch.queueSize)
+	go b.run()
+	return b
+}
+
+func (b *batcher) add(entry *logrus.Entry) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		atomic.AddUint64(&b.counters.dropped, 1)
+		return
+	}
+
+	atomic.AddUint64(&b.counters.queued, 1)
+	select {
+	case b.entryC <- entry:
+	default:
+		atomic.AddUint64(&b.counters.queued, ^uint64(0))
+		atomic.AddUint64(&b.counters.dropped, 1)
+		log.Printf("BurzumLogs: %s queue is full skipping message: %s", b.name, entry.Message)
+	}
+}
+
+func (b *batcher) flush() {
+	select {
+	case b.flushC <- struct{}{}:
+	default:
+	}
+}
+
+// close sends the queued entries.
+func (b *batcher) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.entryC)
+	b.mu.Unlock()
+
+	<-b.done
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.batch.timeout)
+	defer ticker.Stop()
+
+	var batch []*logrus.Entry
+	for {
+		select {
+		case entry, ok := <-b.entryC:
+			if !ok {
+				b.export(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= b.batch.size {
+				b.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			b.export(batch)
+			batch = nil
+		case <-b.flushC:
+			for pending := len(b.entryC); pending > 0; pending-- {
+				entry, ok := <-b.entryC
+				if !ok {
+					break
+				}
+				batch = append(batch, entry)
+				if len(batch) >= b.batch.size {
+					b.export(batch)
+					batch = nil
+				}
+			}
+			b.export(batch)
+			batch = nil
+		}
+	}
+}
+
+func (b *batcher) export(entries []*logrus.Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	failed := b.sendAndRetry(entries)
+	for i := range entries {
+		b.counters.done(i >= failed)
+	}
+}
+
+// sendAndRetry returns the number of entries rejected by the server or given up.
+func (b *batcher) sendAndRetry(entries []*logrus.Entry) int {
+	failed := 0
+	giveUp := func(err error) int {
+		log.Printf("BurzumLogs: unable to send %d entries to %s: %v\n", len(entries), b.name, err)
+		return failed + len(entries)
+	}
+
+	start := time.Now()
+	interval := b.retry.InitialInterval
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+		rejected, err := b.send(ctx, entries)
+		cancel()
+		if err == nil {
+			return failed + rejected
+		}
+
+		retryable, ok := err.(retryableError)
+		if ok && retryable.pending != nil {
+			failed += retryable.rejected
+			entries = retryable.pending
+		}
+		if !ok || b.retry.Disabled {
+			return giveUp(err)
+		}
+
+		// randomized by 50%, as the backoff of the OpenTelemetry exporters
+		wait := interval/2 + time.Duration(rand.Int63n(int64(interval)+1))
+		if retryable.throttle > wait {
+			wait = retryable.throttle
+		}
+		if time.Since(start)+wait > b.retry.MaxElapsedTime {
+			return giveUp(err)
+		}
+		time.Sleep(wait)
+
+		if interval = interval * 3 / 2; interval > b.retry.MaxInterval {
+			interval = b.retry.MaxInterval
+		}
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,14 +13,25 @@ import (
 
 //Config holds the hook configs
 type Config struct {
-	Host            string        `yaml:"host"`
-	MaxRetries      int           `yaml:"max-retries"`
-	RetryDelay      time.Duration `yaml:"retry-delay"`
-	KeepAlivePeriod time.Duration `yaml:"keep-alive"`
-	Buffersize      int           `yaml:"buffer-size"`
-	Formatter       logrus.Formatter
-	Fields          map[string]interface{}
-	MinLevel        logrus.Level
+	Host            string                 `yaml:"host"`
+	Token           string                 `yaml:"token"`
+	MaxRetries      int                    `yaml:"max-retries"`
+	RetryDelay      time.Duration          `yaml:"retry-delay"`
+	KeepAlivePeriod time.Duration          `yaml:"keep-alive"`
+	Buffersize      int                    `yaml:"buffer-size"`
+	Formatter       logrus.Formatter       `yaml:"-"`
+	Fields          map[string]interface{} `yaml:"fields"`
+	MinLevel        logrus.Level           `yaml:"-"`
+	Sampling        *SamplingConfig        `yaml:"sampling"`
+	Dedup           *DedupConfig           `yaml:"dedup"`
+	Redaction       *RedactionConfig       `yaml:"redaction"`
+	MaxEntryBytes   int                    `yaml:"max-entry-bytes"`
+	MaxFieldBytes   int                    `yaml:"max-field-bytes"`
+	Transport       Transport              `yaml:"-"`
+	OTLP            *OTLPConfig            `yaml:"otlp"`
+	Loki            *LokiConfig            `yaml:"loki"`
+	Elasticsearch   *ElasticsearchConfig   `yaml:"elasticsearch"`
+	Fluentd         *FluentdConfig         `yaml:"fluentd"`
 }
 
 var (
@@ -31,41 +42,115 @@ var (
 		RetryDelay:      2 * time.Second,
 		Buffersize:      1000,
 		KeepAlivePeriod: 30 * time.Second,
-		Formatter: &logrus.JSONFormatter{
-			TimestampFormat: time.RFC3339Nano,
-			FieldMap: logrus.FieldMap{
-				logrus.FieldKeyTime:  "time",
-				logrus.FieldKeyLevel: "level",
-				logrus.FieldKeyMsg:   "message",
-			},
-		},
-		MinLevel: logrus.DebugLevel,
+		Formatter:       Formatters["json"](),
+		MinLevel:        logrus.DebugLevel,
+		Transport:       TCPTransport{},
 	}
 )
 
+//Hook is the logrus hook sending the entries to BurzumLogs
+type Hook interface {
+	logrus.Hook
+	io.Closer
+	// Reconfigure swaps the configs of the hook, keeping the entries not sent yet.
+	Reconfigure(config Config) error
+	// Level returns the current MinLevel.
+	Level() logrus.Level
+	// SetLevel changes MinLevel, keeping the other configs and the state of the stages.
+	SetLevel(level logrus.Level) error
+	// Lowered returns the hook firing the entries down to level too, whatever MinLevel, for the loggers lowered
+	// by bzlogging.WithLevel.
+	Lowered(level logrus.Level) logrus.Hook
+	// Stats returns the counters of the entries handled by the hook.
+	Stats() Stats
+	// Flush sends the held entries and waits until the queue is empty, up to timeout.
+	Flush(timeout time.Duration) error
+}
+
 type hook struct {
+	// first field, so the counters are 64-bit aligned
+	counters counters
+
+	// level is the MinLevel, changed by SetLevel without replacing the pipeline
+	level uint32
+
 	mu sync.RWMutex
 
 	conn io.WriteCloser
 
+	// host and transport of the current connection
+	host string
+
+	transport Transport
+
 	entryC chan []byte
 
 	done chan struct{}
 
+	// current holds the *pipeline built from the current configs
+	current atomic.Value
+
+	// lineConfig holds the Config the queued lines are sent with, the one of the last pipeline without a sink, so
+	// the lines left when Reconfigure switches to a sink still go to their endpoint
+	lineConfig atomic.Value
+
+	// sinks tracks the sinks replaced by Reconfigure, until they send their entries
+	sinks sync.WaitGroup
+}
+
+// pipeline holds the configs and the stages derived from them, replaced as a whole by Reconfigure.
+type pipeline struct {
 	config Config
 
 	bztoken string
 
-	minLevel logrus.Level
+	sampler *sampler
+
+	deduper *deduper
+
+	redactor *redactor
+
+	sink sink
 }
 
 //New create a new hook with default configs
-func New(bztoken string) (logrus.Hook, error) {
+func New(bztoken string) (Hook, error) {
 	return NewWithConfig(bztoken, DefaultConfig)
 }
 
-//NewWithConfig create a new hook with custom configs
-func NewWithConfig(bztoken string, config Config) (logrus.Hook, error) {
+//NewWithConfig create a new hook with custom configs, the Buffersize of DefaultConfig is used if it is 0
+func NewWithConfig(bztoken string, config Config) (Hook, error) {
+	if config.Buffersize == 0 {
+		config.Buffersize = DefaultConfig.Buffersize
+	}
+	bz := &hook{
+		conn:   nil,
+		level:  uint32(config.MinLevel),
+		entryC: make(chan []byte, config.Buffersize),
+		done:   make(chan struct{}),
+	}
+	p := bz.newPipeline(bztoken, config)
+	if err := p.startSink(&bz.counters); err != nil {
+		return nil, err
+	}
+	bz.current.Store(p)
+	bz.lineConfig.Store(p.config)
+
+	var err error
+	if p.sink == nil {
+		err = bz.connect()
+	}
+
+	go bz.process()
+
+	return bz, err
+
+}
+
+func (h *hook) newPipeline(bztoken string, config Config) *pipeline {
+	if bztoken == "" {
+		bztoken = config.Token
+	}
 
 	if config.Host == "" {
 		config.Host = DefaultConfig.Host
@@ -75,47 +160,166 @@ func NewWithConfig(bztoken string, config Config) (logrus.Hook, error) {
 		config.Formatter = DefaultConfig.Formatter
 	}
 
-	bz := &hook{
-		conn:     nil,
-		entryC:   make(chan []byte, config.Buffersize),
-		done:     make(chan struct{}),
-		config:   config,
-		bztoken:  bztoken,
-		minLevel: config.MinLevel,
+	if config.Transport == nil {
+		config.Transport = DefaultConfig.Transport
 	}
-	err := bz.connect()
 
-	go bz.process()
+	p := &pipeline{
+		config:  config,
+		bztoken: bztoken,
+	}
+	if config.Sampling != nil {
+		p.sampler = newSampler(*config.Sampling)
+	}
+	if config.Redaction != nil {
+		p.redactor = newRedactor(*config.Redaction)
+	}
+	if config.Dedup != nil {
+		p.deduper = newDeduper(*config.Dedup, func(entry *logrus.Entry) { h.send(p, entry) })
+	}
+	return p
+}
 
-	return bz, err
+func (h *hook) pipeline() *pipeline {
+	return h.current.Load().(*pipeline)
+}
+
+func (h *hook) endpoint() Config {
+	return h.lineConfig.Load().(Config)
+}
+
+// Reconfigure validates config and swaps the level, fields, formatter, stages, host and transport of the hook.
+// The queued entries are kept, and sent to the new host if it changed, or to the previous one when a sink replaces
+// the transport. The entries queued by a replaced sink are sent before it is closed. Buffersize can't be changed and an empty
+// token keeps the current one.
+func (h *hook) Reconfigure(config Config) error {
+	old := h.pipeline()
+	bztoken := config.Token
+	if bztoken == "" {
+		bztoken = old.bztoken
+	}
+	p := h.newPipeline(bztoken, config)
+	if err := p.config.Validate(); err != nil {
+		return err
+	}
+	if err := p.startSink(&h.counters); err != nil {
+		return err
+	}
 
+	h.current.Store(p)
+	if p.sink == nil {
+		h.lineConfig.Store(p.config)
+	}
+	atomic.StoreUint32(&h.level, uint32(config.MinLevel))
+	if old.deduper != nil {
+		old.deduper.flush()
+	}
+	if old.sink != nil {
+		h.sinks.Add(1)
+		go func() {
+			defer h.sinks.Done()
+			old.sink.close()
+		}()
+	}
+	return nil
+}
+
+func (h *hook) Level() logrus.Level {
+	return logrus.Level(atomic.LoadUint32(&h.level))
+}
+
+func (h *hook) SetLevel(level logrus.Level) error {
+	if level >= logrus.Level(len(logrus.AllLevels)) {
+		return fmt.Errorf("logzum: %d is not a valid level", level)
+	}
+	atomic.StoreUint32(&h.level, uint32(level))
+	return nil
 }
 
 func (h *hook) Fire(entry *logrus.Entry) error {
-	h.burzumFields(entry)
-	serialized, err := h.config.Formatter.Format(entry)
+	if entry.Level > h.Level() {
+		return nil
+	}
+	return h.fire(entry)
+}
+
+func (h *hook) Lowered(level logrus.Level) logrus.Hook {
+	return &loweredHook{hook: h, level: level}
+}
+
+// loweredHook fires the entries down to its level too, the requests asking for verbose logs get them whatever
+// the MinLevel of the hook.
+type loweredHook struct {
+	*hook
+
+	level logrus.Level
+}
+
+func (h *loweredHook) Fire(entry *logrus.Entry) error {
+	if entry.Level > h.level && entry.Level > h.Level() {
+		return nil
+	}
+	return h.fire(entry)
+}
+
+func (h *hook) fire(entry *logrus.Entry) error {
+	p := h.pipeline()
+
+	// the entry is shared with the logger and the other hooks
+	entry = copyEntry(entry)
+
+	if p.redactor != nil {
+		p.redactor.redact(entry)
+	}
+	if p.deduper != nil {
+		p.deduper.add(entry)
+		return nil
+	}
+	return h.send(p, entry)
+}
+
+func (h *hook) send(p *pipeline, entry *logrus.Entry) error {
+	if p.sampler != nil {
+		emit, dropped := p.sampler.sample(entry, time.Now())
+		if !emit {
+			return nil
+		}
+		if dropped > 0 {
+			entry.Data[SampledDroppedField] = dropped
+		}
+	}
+
+	if p.sink != nil {
+		p.limitFields(entry)
+		p.sink.add(entry)
+		return nil
+	}
+
+	p.burzumFields(entry)
+	lines, err := p.format(entry)
 	if err != nil {
 		log.Printf("BurzumLogs: error on format %v\n", err)
 		return err
 	}
 
-	select {
-	case h.entryC <- serialized:
-	default:
-		log.Printf("BurzumLogs: sending buffer is full skipping messsage: %s", serialized)
+	for _, serialized := range lines {
+		// counted before it can be sent, so the queue length never goes below zero
+		atomic.AddUint64(&h.counters.queued, 1)
+		select {
+		case h.entryC <- serialized:
+		default:
+			atomic.AddUint64(&h.counters.queued, ^uint64(0))
+			atomic.AddUint64(&h.counters.dropped, 1)
+			log.Printf("BurzumLogs: sending buffer is full skipping messsage: %s", serialized)
+		}
 	}
 	return nil
 }
 
+// Levels returns all the levels, as MinLevel may be changed by Reconfigure after the hook is added to a logger,
+// the entries above it are skipped by Fire, unless fired through Lowered.
 func (h *hook) Levels() []logrus.Level {
-	lvls := make([]logrus.Level, 0, len(logrus.AllLevels))
-	for _, l := range logrus.AllLevels {
-		if h.minLevel >= l {
-			lvls = append(lvls, l)
-		}
-	}
-
-	return lvls
+	return logrus.AllLevels
 }
 
 func (h *hook) process() {
@@ -126,9 +330,13 @@ func (h *hook) process() {
 }
 
 func (h *hook) writeAndRetry(serialized []byte) {
-	for i := 0; i < h.config.MaxRetries; i++ {
+	sent := false
+	defer func() { h.counters.done(sent) }()
+
+	config := h.endpoint()
+	for i := 0; i < config.MaxRetries; i++ {
 		if i > 0 {
-			time.Sleep(h.config.RetryDelay)
+			time.Sleep(config.RetryDelay)
 		}
 
 		if err := h.connect(); err != nil {
@@ -145,45 +353,92 @@ func (h *hook) writeAndRetry(serialized []byte) {
 			continue
 		}
 
+		sent = true
 		break
 	}
 
 }
 
 func (h *hook) Close() error {
+	p := h.pipeline()
+	if p.deduper != nil {
+		p.deduper.flush()
+	}
+	if p.sink != nil {
+		p.sink.close()
+	}
+	h.sinks.Wait()
 	close(h.entryC)
 	<-h.done
-	h.conn.Close()
+	if h.conn != nil {
+		h.conn.Close()
+	}
 	return nil
 }
 
-func (h *hook) burzumFields(entry *logrus.Entry) {
+// startSink starts the sink of the pipeline, when one is configured.
+func (p *pipeline) startSink(counters *counters) (err error) {
+	switch {
+	case p.config.OTLP != nil:
+		p.sink, err = newOTLPExporter(*p.config.OTLP, p.config.Fields, counters)
+	case p.config.Loki != nil:
+		p.sink, err = newLokiSink(*p.config.Loki, p.config.Formatter, p.config.Fields, counters)
+	case p.config.Elasticsearch != nil:
+		p.sink, err = newElasticsearchSink(*p.config.Elasticsearch, p.config.Fields, counters)
+	case p.config.Fluentd != nil:
+		p.sink, err = newFluentdSink(*p.config.Fluentd, p.config.Fields, counters)
+	}
+	return err
+}
+
+func (p *pipeline) burzumFields(entry *logrus.Entry) {
 
-	entry.Data["bztoken"] = h.bztoken
+	// the token authenticates the Burzum line protocol only, the other endpoints would store it
+	switch p.config.Transport.(type) {
+	case TCPTransport, *TCPTransport:
+		entry.Data["bztoken"] = p.bztoken
+	}
 
-	for key, value := range h.config.Fields {
+	for key, value := range p.config.Fields {
 		entry.Data[key] = value
 	}
 
 }
 
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	data := make(logrus.Fields, len(entry.Data)+len(entry.Data)/2)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Context: entry.Context,
+	}
+}
+
 func (h *hook) connect() error {
 	defer h.mu.Unlock()
 	h.mu.Lock()
+	config := h.endpoint()
+	if h.conn != nil && (h.host != config.Host || !sameTransport(h.transport, config.Transport)) {
+		// the endpoint was changed by Reconfigure
+		h.conn.Close()
+		h.conn = nil
+	}
 	if h.conn == nil {
-		tcpAddr, err := net.ResolveTCPAddr("tcp", h.config.Host)
-		if err != nil {
-			return fmt.Errorf("Unable to connect, error: %v", err)
-		}
-		conn, err := net.DialTCP("tcp", nil, tcpAddr)
+		conn, err := config.Transport.Dial(config)
 		if err != nil {
 
 			h.conn = nil
 			return fmt.Errorf("Unable to connect, error: %v", err)
 		}
-		conn.SetKeepAlive(true)
-		conn.SetKeepAlivePeriod(h.config.KeepAlivePeriod)
 		h.conn = conn
+		h.host = config.Host
+		h.transport = config.Transport
 
 	}
 
@@ -1,6 +1,7 @@
 package logzum_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"log"
 	"math/rand"
@@ -15,6 +16,7 @@ import (
 	"net"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -38,6 +40,58 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+func newTestServer(t *testing.T) (string, <-chan []byte) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	lines := make(chan []byte, 100)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+	}()
+	return l.Addr().String(), lines
+}
+
+func newTestHook(t *testing.T, configure func(config *logzum.Config)) (logzum.Hook, <-chan []byte) {
+	host, lines := newTestServer(t)
+
+	config := logzum.DefaultConfig
+	config.Host = host
+	configure(&config)
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h, lines
+}
+
+func receiveRaw(t *testing.T, lines <-chan []byte) []byte {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a log line")
+		return nil
+	}
+}
+
+func receive(t *testing.T, lines <-chan []byte) map[string]interface{} {
+	return decode(t, receiveRaw(t, lines))
+}
+
+func decode(t *testing.T, raw []byte) map[string]interface{} {
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &line))
+	return line
+}
+
 func TestFire(t *testing.T) {
 	var server net.Conn
 
@@ -55,6 +109,7 @@ func TestFire(t *testing.T) {
 				logrus.FieldKeyMsg:   "message",
 			},
 		},
+		MinLevel: logrus.DebugLevel,
 	})
 
 	if err != nil {
@@ -92,21 +147,12 @@ func TestFire(t *testing.T) {
 }
 
 func TestWithMinLevel(t *testing.T) {
-	config := logzum.DefaultConfig
-	config.MinLevel = logrus.InfoLevel
+	h, lines := newTestHook(t, func(config *logzum.Config) { config.MinLevel = logrus.InfoLevel })
 
-	h, err := logzum.NewWithConfig("foo", config)
-	if err != nil {
-		t.Error(err)
-	}
+	assert.Equal(t, logrus.AllLevels, h.Levels())
 
-	expected := []logrus.Level{
-		logrus.InfoLevel,
-		logrus.WarnLevel,
-		logrus.ErrorLevel,
-		logrus.FatalLevel,
-		logrus.PanicLevel,
-	}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "debug", Data: logrus.Fields{}, Level: logrus.DebugLevel}))
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "info", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
 
-	assert.ObjectsAreEqualValues(expected, h.Levels())
+	assert.Equal(t, "info", receive(t, lines)["message"], "entries above MinLevel must be skipped")
 }
@@ -0,0 +1,461 @@
+package logzum
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Formatters are the formatters selectable by name in YAML files and environment variables.
+var Formatters = map[string]func() logrus.Formatter{
+	"json": func() logrus.Formatter {
+		return &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime:  "time",
+				logrus.FieldKeyLevel: "level",
+				logrus.FieldKeyMsg:   "message",
+			},
+		}
+	},
+	"gelf": func() logrus.Formatter {
+		return &GELFFormatter{}
+	},
+	"syslog": func() logrus.Formatter {
+		return &SyslogFormatter{}
+	},
+	"text": func() logrus.Formatter {
+		return &logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			TimestampFormat:  time.RFC3339Nano,
+			QuoteEmptyFields: true,
+		}
+	},
+}
+
+// NewFormatter returns a new formatter registered in Formatters.
+func NewFormatter(name string) (logrus.Formatter, error) {
+	f, ok := Formatters[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(Formatters))
+		for name := range Formatters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown formatter %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return f(), nil
+}
+
+// LoadConfig reads a YAML config file on top of DefaultConfig and validates it.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("logzum: unable to read config: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("logzum: unable to parse %s: %v", path, err)
+	}
+	return config, config.Validate()
+}
+
+// UnmarshalYAML decodes the config, with the formatter and the transport given by name and the minimum level as text.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	var named struct {
+		Formatter string `yaml:"formatter"`
+		Transport string `yaml:"transport"`
+		MinLevel  string `yaml:"min-level"`
+	}
+	if err := unmarshal(&named); err != nil {
+		return err
+	}
+	if named.Formatter != "" {
+		f, err := NewFormatter(named.Formatter)
+		if err != nil {
+			return err
+		}
+		c.Formatter = f
+	}
+	if named.Transport != "" {
+		t, err := NewTransport(named.Transport)
+		if err != nil {
+			return err
+		}
+		c.Transport = t
+	}
+	if named.MinLevel != "" {
+		level, err := logrus.ParseLevel(named.MinLevel)
+		if err != nil {
+			return err
+		}
+		c.MinLevel = level
+	}
+	if c.Fields != nil {
+		c.Fields = stringKeys(c.Fields).(map[string]interface{})
+	}
+	return nil
+}
+
+// UnmarshalYAML decodes mask, drop or hash.
+func (a *RedactAction) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	return a.UnmarshalText([]byte(text))
+}
+
+// UnmarshalYAML decodes the sampling, with the level budgets keyed by level name.
+func (c *SamplingConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SamplingConfig
+	config := plain(*c)
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+
+	var named struct {
+		LevelBudgets map[string]int `yaml:"level-budgets"`
+	}
+	if err := unmarshal(&named); err != nil {
+		return err
+	}
+	if named.LevelBudgets != nil {
+		config.LevelBudgets = make(map[logrus.Level]int, len(named.LevelBudgets))
+		for name, budget := range named.LevelBudgets {
+			level, err := logrus.ParseLevel(name)
+			if err != nil {
+				return err
+			}
+			config.LevelBudgets[level] = budget
+		}
+	}
+	*c = SamplingConfig(config)
+	return nil
+}
+
+// UnmarshalYAML decodes the redaction on top of DefaultRedactionConfig, with the value rules given by the names of
+// DefaultValueRules and the hash key as text. The keys and the values replace the default ones when given.
+func (c *RedactionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RedactionConfig
+	config := plain(DefaultRedactionConfig())
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+
+	var named struct {
+		Values  *[]string `yaml:"values"`
+		HashKey string    `yaml:"hash-key"`
+	}
+	if err := unmarshal(&named); err != nil {
+		return err
+	}
+	if named.Values != nil {
+		config.Values = make([]ValueRule, 0, len(*named.Values))
+		for _, name := range *named.Values {
+			rule, err := defaultValueRule(name)
+			if err != nil {
+				return err
+			}
+			config.Values = append(config.Values, rule)
+		}
+	}
+	if named.HashKey != "" {
+		config.HashKey = []byte(named.HashKey)
+	}
+	*c = RedactionConfig(config)
+	return nil
+}
+
+func defaultValueRule(name string) (ValueRule, error) {
+	names := make([]string, 0, len(DefaultValueRules))
+	for _, rule := range DefaultValueRules {
+		if strings.EqualFold(rule.Name, name) {
+			return rule, nil
+		}
+		names = append(names, rule.Name)
+	}
+	sort.Strings(names)
+	return ValueRule{}, fmt.Errorf("unknown redaction value rule %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// stringKeys converts the maps decoded from YAML, so the fields can be encoded to JSON.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+		return v
+	}
+	return value
+}
+
+// ConfigFromEnv reads the config from environment variables on top of DefaultConfig and validates it.
+// With the BURZUM prefix the variables are:
+//
+//	BURZUM_HOST, BURZUM_TOKEN, BURZUM_MIN_LEVEL, BURZUM_FORMATTER, BURZUM_TRANSPORT,
+//	BURZUM_MAX_RETRIES, BURZUM_RETRY_DELAY, BURZUM_KEEP_ALIVE, BURZUM_BUFFER_SIZE,
+//	BURZUM_MAX_ENTRY_BYTES, BURZUM_MAX_FIELD_BYTES and BURZUM_FIELDS, as in "app=checkout,env=prod".
+//
+// BURZUM_REDACTION_HASH_KEY enables the default redaction, with the HMAC key of the hashed fields.
+//
+// The sinks are enabled by their variables:
+//
+//	BURZUM_OTLP_ENDPOINT, BURZUM_OTLP_PROTOCOL, BURZUM_OTLP_INSECURE,
+//	BURZUM_LOKI_URL, BURZUM_LOKI_TENANT_ID, BURZUM_LOKI_LABELS, as in "service,env,level",
+//	BURZUM_ELASTICSEARCH_URL, BURZUM_ELASTICSEARCH_INDEX,
+//	BURZUM_FLUENTD_ADDRESS, BURZUM_FLUENTD_TAG and BURZUM_FLUENTD_SHARED_KEY.
+func ConfigFromEnv(prefix string) (Config, error) {
+	config := DefaultConfig
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	var problems []string
+	env := func(name string, parse func(value string) error) {
+		value, ok := os.LookupEnv(prefix + name)
+		if !ok || value == "" {
+			return
+		}
+		if err := parse(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s%s: %v", prefix, name, err))
+		}
+	}
+	integer := func(target *int) func(string) error {
+		return func(value string) (err error) {
+			*target, err = strconv.Atoi(value)
+			return err
+		}
+	}
+	duration := func(target *time.Duration) func(string) error {
+		return func(value string) (err error) {
+			*target, err = time.ParseDuration(value)
+			return err
+		}
+	}
+
+	env("HOST", func(value string) error { config.Host = value; return nil })
+	env("TOKEN", func(value string) error { config.Token = value; return nil })
+	env("MIN_LEVEL", func(value string) (err error) {
+		config.MinLevel, err = logrus.ParseLevel(value)
+		return err
+	})
+	env("FORMATTER", func(value string) (err error) {
+		config.Formatter, err = NewFormatter(value)
+		return err
+	})
+	env("TRANSPORT", func(value string) (err error) {
+		config.Transport, err = NewTransport(value)
+		return err
+	})
+	env("MAX_RETRIES", integer(&config.MaxRetries))
+	env("RETRY_DELAY", duration(&config.RetryDelay))
+	env("KEEP_ALIVE", duration(&config.KeepAlivePeriod))
+	env("BUFFER_SIZE", integer(&config.Buffersize))
+	env("MAX_ENTRY_BYTES", integer(&config.MaxEntryBytes))
+	env("MAX_FIELD_BYTES", integer(&config.MaxFieldBytes))
+	env("FIELDS", func(value string) error {
+		config.Fields = make(map[string]interface{})
+		for _, pair := range strings.Split(value, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return fmt.Errorf("expected key=value pairs, got %q", pair)
+			}
+			config.Fields[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+		return nil
+	})
+	env("REDACTION_HASH_KEY", func(value string) error {
+		if config.Redaction == nil {
+			redaction := DefaultRedactionConfig()
+			config.Redaction = &redaction
+		}
+		config.Redaction.HashKey = []byte(value)
+		return nil
+	})
+	otlp := func() *OTLPConfig {
+		if config.OTLP == nil {
+			config.OTLP = &OTLPConfig{}
+		}
+		return config.OTLP
+	}
+	env("OTLP_ENDPOINT", func(value string) error { otlp().Endpoint = value; return nil })
+	env("OTLP_PROTOCOL", func(value string) error { otlp().Protocol = value; return nil })
+	env("OTLP_INSECURE", func(value string) (err error) {
+		otlp().Insecure, err = strconv.ParseBool(value)
+		return err
+	})
+	loki := func() *LokiConfig {
+		if config.Loki == nil {
+			config.Loki = &LokiConfig{}
+		}
+		return config.Loki
+	}
+	env("LOKI_URL", func(value string) error { loki().URL = value; return nil })
+	env("LOKI_TENANT_ID", func(value string) error { loki().TenantID = value; return nil })
+	env("LOKI_LABELS", func(value string) error {
+		l := loki()
+		l.Labels = nil
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				l.Labels = append(l.Labels, field)
+			}
+		}
+		return nil
+	})
+	elasticsearch := func() *ElasticsearchConfig {
+		if config.Elasticsearch == nil {
+			config.Elasticsearch = &ElasticsearchConfig{}
+		}
+		return config.Elasticsearch
+	}
+	env("ELASTICSEARCH_URL", func(value string) error { elasticsearch().URL = value; return nil })
+	env("ELASTICSEARCH_INDEX", func(value string) error { elasticsearch().Index = value; return nil })
+	fluentd := func() *FluentdConfig {
+		if config.Fluentd == nil {
+			config.Fluentd = &FluentdConfig{}
+		}
+		return config.Fluentd
+	}
+	env("FLUENTD_ADDRESS", func(value string) error { fluentd().Address = value; return nil })
+	env("FLUENTD_TAG", func(value string) error { fluentd().Tag = value; return nil })
+	env("FLUENTD_SHARED_KEY", func(value string) error { fluentd().SharedKey = value; return nil })
+
+	if len(problems) > 0 {
+		return config, fmt.Errorf("logzum: invalid environment: %s", strings.Join(problems, "; "))
+	}
+	return config, config.Validate()
+}
+
+// sinks returns the names of the sinks set in place of the transport.
+func (c Config) sinks() []string {
+	var sinks []string
+	if c.OTLP != nil {
+		sinks = append(sinks, "otlp")
+	}
+	if c.Loki != nil {
+		sinks = append(sinks, "loki")
+	}
+	if c.Elasticsearch != nil {
+		sinks = append(sinks, "elasticsearch")
+	}
+	if c.Fluentd != nil {
+		sinks = append(sinks, "fluentd")
+	}
+	return sinks
+}
+
+// Validate reports all the invalid values of the config.
+func (c Config) Validate() error {
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// the sinks have their own endpoint
+	sinks := c.sinks()
+	if len(sinks) == 0 {
+		if c.Host == "" {
+			invalid("host is required")
+		} else if _, port, err := net.SplitHostPort(c.Host); err != nil || port == "" {
+			invalid("host %q must be in the host:port form", c.Host)
+		}
+	}
+	if c.MaxRetries < 1 {
+		invalid("max-retries must be at least 1, got %d", c.MaxRetries)
+	}
+	if c.RetryDelay < 0 {
+		invalid("retry-delay must not be negative, got %s", c.RetryDelay)
+	}
+	if c.KeepAlivePeriod < 0 {
+		invalid("keep-alive must not be negative, got %s", c.KeepAlivePeriod)
+	}
+	if c.Buffersize < 0 {
+		invalid("buffer-size must not be negative, got %d", c.Buffersize)
+	}
+	// only the Loki sink formats the entries
+	if c.Formatter == nil && c.OTLP == nil && c.Elasticsearch == nil && c.Fluentd == nil {
+		invalid("formatter is required")
+	}
+	if c.MinLevel >= logrus.Level(len(logrus.AllLevels)) {
+		invalid("min-level %d is not a valid level", c.MinLevel)
+	}
+	if c.MaxEntryBytes < 0 {
+		invalid("max-entry-bytes must not be negative, got %d", c.MaxEntryBytes)
+	}
+	if c.MaxFieldBytes < 0 {
+		invalid("max-field-bytes must not be negative, got %d", c.MaxFieldBytes)
+	}
+	if s := c.Sampling; s != nil {
+		if s.Interval < 0 || s.First < 0 || s.Thereafter < 0 || s.Rate < 0 || s.Burst < 0 || s.MaxKeys < 0 {
+			invalid("sampling values must not be negative")
+		}
+		for level, budget := range s.LevelBudgets {
+			if budget < 0 {
+				invalid("sampling budget of %s must not be negative, got %d", level, budget)
+			}
+		}
+	}
+	if c.Dedup != nil && c.Dedup.Window < 0 {
+		invalid("dedup window must not be negative, got %s", c.Dedup.Window)
+	}
+	if r := c.Redaction; r != nil {
+		for _, rule := range r.Keys {
+			if rule.Key == "" {
+				invalid("redaction key rules must have a key")
+			}
+		}
+		for _, rule := range r.Values {
+			if rule.Pattern == nil {
+				invalid("redaction value rule %q must have a pattern", rule.Name)
+			}
+		}
+	}
+	if c.OTLP != nil {
+		problems = append(problems, c.OTLP.problems()...)
+	}
+	if c.Loki != nil {
+		problems = append(problems, c.Loki.problems()...)
+	}
+	if c.Elasticsearch != nil {
+		problems = append(problems, c.Elasticsearch.problems()...)
+	}
+	if c.Fluentd != nil {
+		problems = append(problems, c.Fluentd.problems()...)
+	}
+	if len(sinks) > 1 {
+		invalid("%s can't be used together", strings.Join(sinks, " and "))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("logzum: invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package logzum_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "logzum")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "logzum.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+host: localhost:5030
+token: secret
+retry-delay: 500ms
+min-level: warning
+formatter: text
+transport: gelf-tcp
+fields:
+  app: checkout
+  labels:
+    team: payments
+sampling:
+  first: 10
+  level-budgets:
+    debug: 500
+    warning: 50
+dedup:
+  window: 2s
+  ignore-fields: [attempt]
+redaction:
+  keys:
+    - key: password
+      action: drop
+    - key: user_id
+      action: hash
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := logzum.LoadConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:5030", config.Host)
+	assert.Equal(t, "secret", config.Token)
+	assert.Equal(t, 500*time.Millisecond, config.RetryDelay)
+	assert.Equal(t, logzum.DefaultConfig.MaxRetries, config.MaxRetries, "missing values keep the defaults")
+	assert.Equal(t, logrus.WarnLevel, config.MinLevel)
+	assert.IsType(t, &logrus.TextFormatter{}, config.Formatter)
+	assert.Equal(t, logzum.GELFTCPTransport{}, config.Transport)
+	assert.Equal(t, map[string]interface{}{
+		"app":    "checkout",
+		"labels": map[string]interface{}{"team": "payments"},
+	}, config.Fields)
+	assert.Equal(t, &logzum.SamplingConfig{
+		First:        10,
+		LevelBudgets: map[logrus.Level]int{logrus.DebugLevel: 500, logrus.WarnLevel: 50},
+	}, config.Sampling)
+	assert.Equal(t, &logzum.DedupConfig{Window: 2 * time.Second, IgnoreFields: []string{"attempt"}}, config.Dedup)
+	assert.Equal(t, []logzum.KeyRule{
+		{Key: "password", Action: logzum.RedactDrop},
+		{Key: "user_id", Action: logzum.RedactHash},
+	}, config.Redaction.Keys)
+	assert.Equal(t, []string{"cnpj", "cpf", "card", "email"}, ruleNames(config.Redaction.Values), "the value rules are kept")
+}
+
+func TestLoadConfigRedaction(t *testing.T) {
+	load := func(content string) *logzum.RedactionConfig {
+		path := writeConfig(t, content)
+		defer os.RemoveAll(filepath.Dir(path))
+		config, err := logzum.LoadConfig(path)
+		require.NoError(t, err, content)
+		return config.Redaction
+	}
+
+	redaction := load("redaction: {mask: '***'}")
+	assert.Equal(t, "***", redaction.Mask)
+	assert.Equal(t, logzum.DefaultKeyRules, redaction.Keys, "a redaction block keeps the default rules")
+	assert.Equal(t, []string{"cnpj", "cpf", "card", "email"}, ruleNames(redaction.Values))
+
+	assert.Equal(t, []string{"cpf", "email"}, ruleNames(load("redaction: {values: [cpf, Email]}").Values))
+	assert.Empty(t, load("redaction: {values: []}").Values)
+	assert.Empty(t, redaction.HashKey)
+	assert.Equal(t, []byte("s3cr3t"), load("redaction: {hash-key: s3cr3t}").HashKey)
+}
+
+func ruleNames(rules []logzum.ValueRule) []string {
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	_, err := logzum.LoadConfig(filepath.Join(os.TempDir(), "logzum-missing.yaml"))
+	assert.Error(t, err)
+
+	for content, expected := range map[string]string{
+		"formatter: xml":    `unknown formatter "xml"`,
+		"transport: smtp":   `unknown transport "smtp"`,
+		"min-level: loud":   `not a valid logrus Level: "loud"`,
+		"retry-delay: soon": `soon`,
+		"host: localhost":   `host "localhost" must be in the host:port form`,
+		"redaction: {keys: [{key: a, action: shred}]}": `unknown redact action "shred"`,
+		"redaction: {values: [phone]}":                 `unknown redaction value rule "phone", expected one of card, cnpj, cpf, email`,
+		"sampling: {level-budgets: {loud: 1}}":         `not a valid logrus Level: "loud"`,
+		"sampling: {level-budgets: {info: -1}}":        `sampling budget of info must not be negative, got -1`,
+	} {
+		path := writeConfig(t, content)
+		_, err := logzum.LoadConfig(path)
+		os.RemoveAll(filepath.Dir(path))
+		if assert.Error(t, err, content) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"BURZUM_HOST":        "localhost:5030",
+		"BURZUM_TOKEN":       "secret",
+		"BURZUM_MIN_LEVEL":   "info",
+		"BURZUM_RETRY_DELAY": "1s",
+		"BURZUM_MAX_RETRIES": "5",
+		"BURZUM_FIELDS":      "app=checkout, env=prod",
+		"BURZUM_FORMATTER":   "gelf",
+		"BURZUM_TRANSPORT":   "gelf-udp",
+
+		"BURZUM_REDACTION_HASH_KEY": "s3cr3t",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	config, err := logzum.ConfigFromEnv("BURZUM")
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:5030", config.Host)
+	assert.Equal(t, "secret", config.Token)
+	assert.Equal(t, logrus.InfoLevel, config.MinLevel)
+	assert.Equal(t, time.Second, config.RetryDelay)
+	assert.Equal(t, 5, config.MaxRetries)
+	assert.Equal(t, map[string]interface{}{"app": "checkout", "env": "prod"}, config.Fields)
+	assert.Equal(t, logzum.DefaultConfig.Buffersize, config.Buffersize)
+	assert.IsType(t, &logzum.GELFFormatter{}, config.Formatter)
+	assert.Equal(t, logzum.GELFUDPTransport{}, config.Transport)
+	if assert.NotNil(t, config.Redaction) {
+		assert.Equal(t, []byte("s3cr3t"), config.Redaction.HashKey)
+		assert.Equal(t, logzum.DefaultKeyRules, config.Redaction.Keys, "the hash key enables the default redaction")
+	}
+
+	os.Setenv("BURZUM_MAX_RETRIES", "many")
+	os.Setenv("BURZUM_KEEP_ALIVE", "-1s")
+	defer os.Unsetenv("BURZUM_KEEP_ALIVE")
+
+	_, err = logzum.ConfigFromEnv("BURZUM_")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "BURZUM_MAX_RETRIES")
+	}
+
+	os.Setenv("BURZUM_MAX_RETRIES", "5")
+	_, err = logzum.ConfigFromEnv("BURZUM_")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "keep-alive must not be negative")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, logzum.DefaultConfig.Validate())
+
+	err := logzum.Config{MaxRetries: -1, Buffersize: -1}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "host is required")
+		assert.Contains(t, err.Error(), "max-retries must be at least 1, got -1")
+		assert.Contains(t, err.Error(), "buffer-size must not be negative")
+		assert.Contains(t, err.Error(), "formatter is required")
+	}
+}
@@ -0,0 +1,147 @@
+package logzum
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// RepeatCountField is the log field with the number of identical entries collapsed into one.
+	RepeatCountField = "repeat.count"
+	// RepeatFirstField is the log field with the time of the first collapsed entry.
+	RepeatFirstField = "repeat.first"
+	// RepeatLastField is the log field with the time of the last collapsed entry.
+	RepeatLastField = "repeat.last"
+)
+
+//DedupConfig holds the duplicate suppression configs
+type DedupConfig struct {
+	// Window is how long identical consecutive entries are collapsed, one second by default.
+	// Entries are held by the hook up to Window before being sent.
+	Window time.Duration `yaml:"window"`
+	// IgnoreFields are not compared by the default equality, e.g. attempt numbers.
+	IgnoreFields []string `yaml:"ignore-fields"`
+	// Equal reports whether two entries are identical, replacing the default equality.
+	Equal func(a, b *logrus.Entry) bool `yaml:"-"`
+}
+
+// EqualEntries reports whether a and b have the same level, message and fields, ignoring the given fields.
+func EqualEntries(a, b *logrus.Entry, ignore ...string) bool {
+	if a.Level != b.Level || a.Message != b.Message {
+		return false
+	}
+	skip := func(key string) bool {
+		for _, i := range ignore {
+			if i == key {
+				return true
+			}
+		}
+		return false
+	}
+	n := 0
+	for key, value := range a.Data {
+		if skip(key) {
+			continue
+		}
+		other, ok := b.Data[key]
+		if !ok || !reflect.DeepEqual(value, other) {
+			return false
+		}
+		n++
+	}
+	for key := range b.Data {
+		if !skip(key) {
+			n--
+		}
+	}
+	return n == 0
+}
+
+type deduper struct {
+	mu sync.Mutex
+
+	config DedupConfig
+
+	emit func(entry *logrus.Entry)
+
+	pending *logrus.Entry
+
+	count int
+
+	started time.Time
+
+	first, last time.Time
+
+	timer *time.Timer
+}
+
+func newDeduper(config DedupConfig, emit func(entry *logrus.Entry)) *deduper {
+	if config.Window <= 0 {
+		config.Window = time.Second
+	}
+	if config.Equal == nil {
+		ignore := config.IgnoreFields
+		config.Equal = func(a, b *logrus.Entry) bool {
+			return EqualEntries(a, b, ignore...)
+		}
+	}
+	return &deduper{config: config, emit: emit}
+}
+
+// add holds entry while the identical entries following it are counted, the previous one is emitted.
+func (d *deduper) add(entry *logrus.Entry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	at := entry.Time
+	if at.IsZero() {
+		at = now
+	}
+
+	if d.pending != nil && now.Sub(d.started) < d.config.Window && d.config.Equal(d.pending, entry) {
+		d.count++
+		d.last = at
+		return
+	}
+
+	d.flushLocked()
+	d.pending = entry
+	d.count = 1
+	d.started = now
+	d.first, d.last = at, at
+	d.timer = time.AfterFunc(d.config.Window, func() { d.expire(entry) })
+}
+
+func (d *deduper) expire(entry *logrus.Entry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending == entry {
+		d.flushLocked()
+	}
+}
+
+func (d *deduper) flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flushLocked()
+}
+
+func (d *deduper) flushLocked() {
+	if d.pending == nil {
+		return
+	}
+	d.timer.Stop()
+
+	entry := d.pending
+	d.pending = nil
+	if d.count > 1 {
+		entry.Data[RepeatCountField] = d.count
+		entry.Data[RepeatFirstField] = d.first
+		entry.Data[RepeatLastField] = d.last
+	}
+	d.emit(entry)
+}
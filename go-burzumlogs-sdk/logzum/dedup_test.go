@@ -0,0 +1,100 @@
+package logzum_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDedupHook(t *testing.T, dedup logzum.DedupConfig) (logrus.Hook, <-chan []byte) {
+	return newTestHook(t, func(config *logzum.Config) { config.Dedup = &dedup })
+}
+
+func fireAt(t *testing.T, h logrus.Hook, at time.Time, msg string, data logrus.Fields) {
+	require.NoError(t, h.Fire(&logrus.Entry{Message: msg, Data: data, Level: logrus.WarnLevel, Time: at}))
+}
+
+func TestDedupCollapsesConsecutiveEntries(t *testing.T) {
+	h, lines := newDedupHook(t, logzum.DedupConfig{Window: time.Hour})
+
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		fireAt(t, h, start.Add(time.Duration(i)*time.Second), "retrying", logrus.Fields{"host": "db"})
+	}
+	fireAt(t, h, start.Add(3*time.Second), "retrying", logrus.Fields{"host": "cache"})
+	fireAt(t, h, start.Add(4*time.Second), "retrying", logrus.Fields{"host": "db"})
+	require.NoError(t, h.(io.Closer).Close())
+
+	line := receive(t, lines)
+	assert.Equal(t, "db", line["host"])
+	assert.Equal(t, float64(3), line[logzum.RepeatCountField])
+	assert.Equal(t, "2018-01-01T00:00:00Z", line[logzum.RepeatFirstField])
+	assert.Equal(t, "2018-01-01T00:00:02Z", line[logzum.RepeatLastField])
+
+	line = receive(t, lines)
+	assert.Equal(t, "cache", line["host"])
+	assert.NotContains(t, line, logzum.RepeatCountField)
+
+	line = receive(t, lines)
+	assert.Equal(t, "db", line["host"], "only consecutive entries are collapsed")
+	assert.NotContains(t, line, logzum.RepeatCountField)
+}
+
+func TestDedupIgnoreFields(t *testing.T) {
+	h, lines := newDedupHook(t, logzum.DedupConfig{Window: time.Hour, IgnoreFields: []string{"attempt"}})
+
+	for i := 1; i <= 4; i++ {
+		fireAt(t, h, time.Now(), "retrying", logrus.Fields{"attempt": i})
+	}
+	require.NoError(t, h.(io.Closer).Close())
+
+	line := receive(t, lines)
+	assert.Equal(t, float64(1), line["attempt"])
+	assert.Equal(t, float64(4), line[logzum.RepeatCountField])
+}
+
+func TestDedupCustomEqual(t *testing.T) {
+	h, lines := newDedupHook(t, logzum.DedupConfig{
+		Window: time.Hour,
+		Equal:  func(a, b *logrus.Entry) bool { return a.Message == b.Message },
+	})
+
+	fireAt(t, h, time.Now(), "timeout", logrus.Fields{"a": 1})
+	fireAt(t, h, time.Now(), "timeout", logrus.Fields{"b": 2})
+	require.NoError(t, h.(io.Closer).Close())
+
+	assert.Equal(t, float64(2), receive(t, lines)[logzum.RepeatCountField])
+}
+
+func TestDedupWindowExpires(t *testing.T) {
+	h, lines := newDedupHook(t, logzum.DedupConfig{Window: 50 * time.Millisecond})
+
+	fireAt(t, h, time.Now(), "retrying", logrus.Fields{})
+	fireAt(t, h, time.Now(), "retrying", logrus.Fields{})
+
+	line := receive(t, lines)
+	assert.Equal(t, float64(2), line[logzum.RepeatCountField], "the held entry is sent when the window expires")
+
+	time.Sleep(60 * time.Millisecond)
+	fireAt(t, h, time.Now(), "retrying", logrus.Fields{})
+	assert.NotContains(t, receive(t, lines), logzum.RepeatCountField)
+}
+
+func TestEqualEntries(t *testing.T) {
+	a := &logrus.Entry{Message: "m", Level: logrus.InfoLevel, Data: logrus.Fields{"k": []int{1}, "n": 1}}
+	b := &logrus.Entry{Message: "m", Level: logrus.InfoLevel, Data: logrus.Fields{"k": []int{1}, "n": 2}}
+
+	assert.False(t, logzum.EqualEntries(a, b))
+	assert.True(t, logzum.EqualEntries(a, b, "n"))
+
+	b.Data["extra"] = true
+	assert.False(t, logzum.EqualEntries(a, b, "n"))
+
+	b = &logrus.Entry{Message: "m", Level: logrus.WarnLevel, Data: a.Data}
+	assert.False(t, logzum.EqualEntries(a, b))
+}
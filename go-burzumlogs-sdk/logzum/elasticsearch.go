@@ -0,0 +1,357 @@
+package logzum
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultElasticsearchIndex is the index template used when none is set.
+const DefaultElasticsearchIndex = "logzum-{yyyy.MM.dd}"
+
+// ElasticsearchConfig writes the entries to the Elasticsearch bulk API, in place of the formatter and the transport of
+// the hook. The documents have the @timestamp, level and message keys and the fields.
+type ElasticsearchConfig struct {
+	// URL of Elasticsearch, http://localhost:9200 by default. /_bulk is added to it.
+	URL string `yaml:"url"`
+	// Index is the template of the index names, as logs-{service}-{yyyy.MM.dd}. {yyyy}, {yy}, {MM}, {dd} and {HH}
+	// patterns are replaced by the UTC time of the entry and the other names between braces by the fields, lowercased.
+	// DefaultElasticsearchIndex is used when empty.
+	Index string `yaml:"index"`
+	// OpType is the bulk action, index by default or create, required by data streams.
+	OpType string `yaml:"op-type"`
+	// Username and Password are sent as basic auth when Username is set.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// APIKey is sent as the ApiKey authorization when set.
+	APIKey string `yaml:"api-key"`
+	// Headers are sent on every request.
+	Headers map[string]string `yaml:"headers"`
+	// TLSConfig is used on https URLs.
+	TLSConfig *tls.Config `yaml:"-"`
+	// DeadLetter receives the documents rejected by Elasticsearch, as mapping conflicts, which are not retried.
+	DeadLetter func(failure BulkFailure) `yaml:"-"`
+	// Timeout of each bulk request, 10s by default.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize is the maximum number of documents per bulk request, 512 by default.
+	BatchSize int `yaml:"batch-size"`
+	// BatchTimeout is the maximum delay of an entry before its batch is sent, 1s by default.
+	BatchTimeout time.Duration `yaml:"batch-timeout"`
+	// QueueSize is the maximum number of entries waiting for a batch, 2048 by default. Entries above it are dropped.
+	QueueSize int `yaml:"queue-size"`
+	// Retry is the backoff of the requests and of the documents failed with 429 or 5xx. Only the failed documents
+	// of a bulk request are sent again.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+// BulkFailure is a document rejected by Elasticsearch.
+type BulkFailure struct {
+	Entry    *logrus.Entry
+	Index    string
+	Document json.RawMessage
+	// Status is the HTTP status of the item, as 400.
+	Status int
+	// Type is the error type, as mapper_parsing_exception on mapping conflicts.
+	Type   string
+	Reason string
+}
+
+func (c ElasticsearchConfig) withDefaults() ElasticsearchConfig {
+	if c.URL == "" {
+		c.URL = "http://localhost:9200"
+	}
+	if c.Index == "" {
+		c.Index = DefaultElasticsearchIndex
+	}
+	if c.OpType == "" {
+		c.OpType = "index"
+	}
+	return c
+}
+
+func (c ElasticsearchConfig) batch() batchConfig {
+	return batchConfig{size: c.BatchSize, timeout: c.BatchTimeout, queueSize: c.QueueSize}
+}
+
+func (c ElasticsearchConfig) problems() []string {
+	var problems []string
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("elasticsearch url %q must be an absolute URL", c.URL))
+		}
+	}
+	if _, err := parseIndexTemplate(c.Index); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.OpType != "" && c.OpType != "index" && c.OpType != "create" {
+		problems = append(problems, fmt.Sprintf("elasticsearch op-type must be index or create, got %q", c.OpType))
+	}
+	return append(problems, batchProblems("elasticsearch", c.Timeout, c.batch(), c.Retry)...)
+}
+
+// indexPart is a literal, a field or a time layout of an index template.
+type indexPart struct {
+	literal string
+	field   string
+	layout  string
+}
+
+var indexLayouts = strings.NewReplacer("yyyy", "2006", "yy", "06", "MM", "01", "dd", "02", "HH", "15")
+
+// parseIndexTemplate splits template in parts, the patterns made only of date letters and separators being layouts.
+func parseIndexTemplate(template string) ([]indexPart, error) {
+	var parts []indexPart
+	for rest := template; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return nil, fmt.Errorf("elasticsearch index %q has an unopened }", template)
+			}
+			parts = append(parts, indexPart{literal: rest})
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("elasticsearch index %q has an unclosed {", template)
+		}
+		if open > 0 {
+			parts = append(parts, indexPart{literal: rest[:open]})
+		}
+		pattern := rest[open+1 : open+end]
+		switch {
+		case pattern == "":
+			return nil, fmt.Errorf("elasticsearch index %q has an empty {}", template)
+		case strings.Trim(pattern, "yMdH.-_/") == "":
+			parts = append(parts, indexPart{layout: indexLayouts.Replace(pattern)})
+		default:
+			parts = append(parts, indexPart{field: pattern})
+		}
+		rest = rest[open+end+1:]
+	}
+	return parts, nil
+}
+
+// indexName replaces the characters invalid in index names by -.
+var indexName = strings.NewReplacer(`\`, "-", "/", "-", "*", "-", "?", "-", `"`, "-", "<", "-", ">", "-", "|", "-",
+	" ", "-", ",", "-", "#", "-", ":", "-")
+
+// elasticsearchSink is the sink sending batches of documents to the bulk API.
+type elasticsearchSink struct {
+	*batcher
+	config ElasticsearchConfig
+	url    string
+	index  []indexPart
+	fields map[string]interface{}
+	client *http.Client
+}
+
+// newElasticsearchSink starts a sink, with fields added to all the documents.
+func newElasticsearchSink(config ElasticsearchConfig, fields map[string]interface{}, counters *counters) (*elasticsearchSink, error) {
+	config = config.withDefaults()
+
+	index, err := parseIndexTemplate(config.Index)
+	if err != nil {
+		return nil, fmt.Errorf("logzum: %v", err)
+	}
+
+	s := &elasticsearchSink{
+		config: config,
+		url:    strings.TrimRight(config.URL, "/") + "/_bulk",
+		index:  index,
+		fields: fields,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: config.TLSConfig,
+			},
+		},
+	}
+	s.batcher = newBatcher("Elasticsearch", config.batch(), config.Retry, config.Timeout, counters, s.send)
+	return s, nil
+}
+
+func (s *elasticsearchSink) add(entry *logrus.Entry) {
+	for key, value := range s.fields {
+		entry.Data[key] = value
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	s.batcher.add(entry)
+}
+
+func (s *elasticsearchSink) close() error {
+	s.batcher.close()
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *elasticsearchSink) endpoint() string {
+	return s.url
+}
+
+// indexOf returns the index of entry, with unknown in place of the missing fields.
+func (s *elasticsearchSink) indexOf(entry *logrus.Entry) string {
+	var name strings.Builder
+	for _, part := range s.index {
+		switch {
+		case part.layout != "":
+			name.WriteString(entry.Time.UTC().Format(part.layout))
+		case part.field != "":
+			value, ok := entry.Data[part.field]
+			if !ok || fmt.Sprint(value) == "" {
+				value = "unknown"
+			}
+			name.WriteString(indexName.Replace(strings.ToLower(fmt.Sprint(value))))
+		default:
+			name.WriteString(part.literal)
+		}
+	}
+	return name.String()
+}
+
+func elasticsearchDocument(entry *logrus.Entry) ([]byte, error) {
+	document := make(map[string]interface{}, len(entry.Data)+3)
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		document[key] = value
+	}
+	document["@timestamp"] = entry.Time.Format(time.RFC3339Nano)
+	document["level"] = entry.Level.String()
+	document["message"] = entry.Message
+	return json.Marshal(document)
+}
+
+type bulkItem struct {
+	Index  string `json:"_index"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+type bulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status/100 == 5
+}
+
+func (s *elasticsearchSink) send(ctx context.Context, entries []*logrus.Entry) (int, error) {
+	var body bytes.Buffer
+	var sent []*logrus.Entry
+	var documents [][]byte
+	var indices []string
+	rejected := 0
+	for _, entry := range entries {
+		document, err := elasticsearchDocument(entry)
+		if err != nil {
+			log.Printf("BurzumLogs: error on format %v\n", err)
+			rejected++
+			continue
+		}
+		index := s.indexOf(entry)
+		action, _ := json.Marshal(map[string]interface{}{s.config.OpType: map[string]string{"_index": index}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(document)
+		body.WriteByte('\n')
+
+		sent = append(sent, entry)
+		documents = append(documents, document)
+		indices = append(indices, index)
+	}
+	if len(sent) == 0 {
+		return rejected, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.config.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return 0, retryableError{err: err}
+	}
+	if retryableStatus(resp.StatusCode) {
+		err := fmt.Errorf("logzum: Elasticsearch answered %s", resp.Status)
+		return 0, retryableError{err: err, throttle: retryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("logzum: Elasticsearch answered %s: %s", resp.Status, bytes.TrimSpace(content))
+	}
+
+	var response bulkResponse
+	if err := json.Unmarshal(content, &response); err != nil {
+		return 0, fmt.Errorf("logzum: invalid Elasticsearch bulk response: %v", err)
+	}
+	if !response.Errors {
+		return rejected, nil
+	}
+	if len(response.Items) != len(sent) {
+		return 0, fmt.Errorf("logzum: Elasticsearch answered %d bulk items for %d documents", len(response.Items), len(sent))
+	}
+
+	var pending []*logrus.Entry
+	var last error
+	for i, result := range response.Items {
+		for _, item := range result {
+			if item.Error == nil && item.Status/100 == 2 {
+				continue
+			}
+			failure := BulkFailure{Entry: sent[i], Index: indices[i], Document: documents[i], Status: item.Status}
+			if item.Error != nil {
+				failure.Type, failure.Reason = item.Error.Type, item.Error.Reason
+			}
+			last = fmt.Errorf("logzum: Elasticsearch answered %d on %s: %s %s", item.Status, indices[i], failure.Type, failure.Reason)
+			if retryableStatus(item.Status) {
+				pending = append(pending, sent[i])
+				continue
+			}
+
+			rejected++
+			if s.config.DeadLetter != nil {
+				s.config.DeadLetter(failure)
+			} else {
+				log.Printf("BurzumLogs: %v\n", last)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		return 0, retryableError{err: last, pending: pending, rejected: rejected}
+	}
+	return rejected, nil
+}
@@ -0,0 +1,282 @@
+package logzum_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+type bulkDocument struct {
+	Action   string
+	Index    string
+	Document map[string]interface{}
+}
+
+// fakeElasticsearch is a bulk API answering each document with the status and error type of respond.
+type fakeElasticsearch struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests chan []bulkDocument
+	headers  chan http.Header
+}
+
+func newFakeElasticsearch(t *testing.T, respond func(doc bulkDocument) (int, string), statuses ...int) *fakeElasticsearch {
+	es := &fakeElasticsearch{
+		statuses: statuses,
+		requests: make(chan []bulkDocument, 100),
+		headers:  make(chan http.Header, 100),
+	}
+	es.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		es.headers <- r.Header
+
+		es.mu.Lock()
+		status := http.StatusOK
+		if len(es.statuses) > 0 {
+			status, es.statuses = es.statuses[0], es.statuses[1:]
+		}
+		es.mu.Unlock()
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		var docs []bulkDocument
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+			require.True(t, scanner.Scan(), "an action is followed by its document")
+			var doc bulkDocument
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc.Document))
+			for name, meta := range action {
+				doc.Action, doc.Index = name, meta["_index"]
+			}
+			docs = append(docs, doc)
+		}
+		es.requests <- docs
+
+		response := map[string]interface{}{"took": 3, "errors": false}
+		var items []interface{}
+		for _, doc := range docs {
+			status, errorType := respond(doc)
+			item := map[string]interface{}{"_index": doc.Index, "status": status}
+			if errorType != "" {
+				item["error"] = map[string]string{"type": errorType, "reason": "failed to parse field [status]"}
+				response["errors"] = true
+			}
+			items = append(items, map[string]interface{}{doc.Action: item})
+		}
+		response["items"] = items
+		json.NewEncoder(w).Encode(response)
+	}))
+	return es
+}
+
+func accept(bulkDocument) (int, string) {
+	return http.StatusCreated, ""
+}
+
+func receiveBulk(t *testing.T, requests <-chan []bulkDocument) []bulkDocument {
+	select {
+	case docs := <-requests:
+		return docs
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a bulk request")
+		return nil
+	}
+}
+
+func newElasticsearchHook(t *testing.T, es logzum.ElasticsearchConfig) logzum.Hook {
+	if es.BatchTimeout == 0 {
+		es.BatchTimeout = time.Hour
+	}
+	es.Retry.InitialInterval = 10 * time.Millisecond
+
+	config := logzum.DefaultConfig
+	config.Fields = map[string]interface{}{"service": "Checkout API"}
+	config.Elasticsearch = &es
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	es := newFakeElasticsearch(t, accept)
+	defer es.Close()
+
+	h := newElasticsearchHook(t, logzum.ElasticsearchConfig{
+		URL:    es.URL,
+		Index:  "logs-{service}-{yyyy.MM.dd}",
+		APIKey: "secret",
+	})
+	defer h.Close()
+
+	now := time.Date(2019, 10, 1, 23, 0, 0, 0, time.FixedZone("BRT", -3*60*60))
+	require.NoError(t, h.Fire(&logrus.Entry{
+		Time:    now,
+		Level:   logrus.ErrorLevel,
+		Message: "payment declined",
+		Data:    logrus.Fields{"error": errors.New("boom"), "attempt": 2},
+	}))
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "no time", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	docs := receiveBulk(t, es.requests)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "index", docs[0].Action)
+	assert.Equal(t, "logs-checkout-api-2019.10.02", docs[0].Index, "lowercased fields and UTC dates")
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp": "2019-10-01T23:00:00-03:00",
+		"level":      "error",
+		"message":    "payment declined",
+		"error":      "boom",
+		"attempt":    float64(2),
+		"service":    "Checkout API",
+	}, docs[0].Document)
+	assert.NotEmpty(t, docs[1].Document["@timestamp"], "entries without time are stamped when received")
+
+	headers := <-es.headers
+	assert.Equal(t, "application/x-ndjson", headers.Get("Content-Type"))
+	assert.Equal(t, "ApiKey secret", headers.Get("Authorization"))
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, es.URL+"/_bulk", stats.Host)
+}
+
+func TestElasticsearchIndexTemplate(t *testing.T) {
+	es := newFakeElasticsearch(t, accept)
+	defer es.Close()
+
+	h := newElasticsearchHook(t, logzum.ElasticsearchConfig{
+		URL:    es.URL + "/",
+		Index:  "{team}_logs-{yy}{MM}-{HH}h",
+		OpType: "create",
+	})
+	defer h.Close()
+
+	now := time.Date(2019, 10, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, h.Fire(&logrus.Entry{Time: now, Data: logrus.Fields{"team": "Pay/Ments"}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Time: now, Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	docs := receiveBulk(t, es.requests)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "create", docs[0].Action)
+	assert.Equal(t, "pay-ments_logs-1910-09h", docs[0].Index)
+	assert.Equal(t, "unknown_logs-1910-09h", docs[1].Index, "missing fields")
+}
+
+func TestElasticsearchItemRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	es := newFakeElasticsearch(t, func(doc bulkDocument) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		message := doc.Document["message"].(string)
+		attempts[message]++
+		switch {
+		case message == "overloaded" && attempts[message] == 1:
+			return http.StatusTooManyRequests, "es_rejected_execution_exception"
+		case message == "conflict":
+			return http.StatusBadRequest, "mapper_parsing_exception"
+		}
+		return http.StatusCreated, ""
+	})
+	defer es.Close()
+
+	failures := make(chan logzum.BulkFailure, 10)
+	h := newElasticsearchHook(t, logzum.ElasticsearchConfig{
+		URL:        es.URL,
+		DeadLetter: func(failure logzum.BulkFailure) { failures <- failure },
+	})
+	defer h.Close()
+
+	for _, message := range []string{"ok", "overloaded", "conflict"} {
+		require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: message, Data: logrus.Fields{}}))
+	}
+	require.NoError(t, h.Flush(2*time.Second))
+
+	assert.Len(t, receiveBulk(t, es.requests), 3)
+	retried := receiveBulk(t, es.requests)
+	require.Len(t, retried, 1, "only the failed documents are retried")
+	assert.Equal(t, "overloaded", retried[0].Document["message"])
+
+	require.Len(t, failures, 1)
+	failure := <-failures
+	assert.Equal(t, "conflict", failure.Entry.Message)
+	assert.Equal(t, http.StatusBadRequest, failure.Status)
+	assert.Equal(t, "mapper_parsing_exception", failure.Type)
+	assert.Equal(t, "failed to parse field [status]", failure.Reason)
+	assert.Contains(t, string(failure.Document), `"message":"conflict"`)
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestElasticsearchRequestErrors(t *testing.T) {
+	es := newFakeElasticsearch(t, accept, http.StatusServiceUnavailable, http.StatusOK, http.StatusUnauthorized)
+	defer es.Close()
+
+	h := newElasticsearchHook(t, logzum.ElasticsearchConfig{URL: es.URL, BatchSize: 1, Username: "user", Password: "secret"})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "retried", Data: logrus.Fields{}}))
+	assert.Equal(t, "retried", receiveBulk(t, es.requests)[0].Document["message"])
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "unauthorized", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", (<-es.headers).Get("Authorization"))
+	stats := h.Stats()
+	assert.Equal(t, uint64(1), stats.Sent)
+	assert.Equal(t, uint64(1), stats.Failed, "401 is not retried")
+}
+
+func TestElasticsearchConfig(t *testing.T) {
+	os.Setenv("BURZUM_ELASTICSEARCH_URL", "http://es:9200")
+	defer os.Unsetenv("BURZUM_ELASTICSEARCH_URL")
+	os.Setenv("BURZUM_ELASTICSEARCH_INDEX", "logs-{service}-{yyyy.MM.dd}")
+	defer os.Unsetenv("BURZUM_ELASTICSEARCH_INDEX")
+
+	config, err := logzum.ConfigFromEnv("BURZUM")
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.ElasticsearchConfig{URL: "http://es:9200", Index: "logs-{service}-{yyyy.MM.dd}"}, config.Elasticsearch)
+
+	for index, expected := range map[string]string{
+		"logs-{service":     `elasticsearch index "logs-{service" has an unclosed {`,
+		"logs-service}":     `elasticsearch index "logs-service}" has an unopened }`,
+		"logs-{}-{yyyy.MM}": `elasticsearch index "logs-{}-{yyyy.MM}" has an empty {}`,
+	} {
+		err := logzum.Config{Elasticsearch: &logzum.ElasticsearchConfig{Index: index}, MaxRetries: 1}.Validate()
+		if assert.Error(t, err, index) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+
+	err = logzum.Config{
+		Elasticsearch: &logzum.ElasticsearchConfig{OpType: "update"},
+		Loki:          &logzum.LokiConfig{},
+		MaxRetries:    1,
+	}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `elasticsearch op-type must be index or create, got "update"`)
+		assert.Contains(t, err.Error(), "loki and elasticsearch can't be used together")
+	}
+}
@@ -0,0 +1,345 @@
+package logzum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+)
+
+// ErrFluentdAuth is returned when Fluentd refuses the shared key handshake or answers it with an invalid digest.
+var ErrFluentdAuth = errors.New("logzum: fluentd authentication failed")
+
+// FluentdConfig sends the entries to Fluentd or Fluent Bit with the forward protocol, in place of the formatter and
+// the transport of the hook. Each batch is a CompressedPackedForward message of [time, record] entries, the record
+// holding the level, the message and the fields.
+type FluentdConfig struct {
+	// Address is the host:port of the forward input, localhost:24224 by default.
+	Address string `yaml:"address"`
+	// Tag of the entries, logzum by default.
+	Tag string `yaml:"tag"`
+	// TLSConfig enables TLS when set.
+	TLSConfig *tls.Config `yaml:"-"`
+	// SharedKey enables the handshake of secure forward inputs.
+	SharedKey string `yaml:"shared-key"`
+	// Hostname is sent in the handshake, the hostname of the machine by default.
+	Hostname string `yaml:"hostname"`
+	// Username and Password are sent in the handshake when the input requires user authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// DisableCompression sends the entries without gzip.
+	DisableCompression bool `yaml:"disable-compression"`
+	// RequireAck waits for the acknowledgement of each batch, which is sent again when it does not arrive within
+	// Timeout, for at-least-once delivery.
+	RequireAck bool `yaml:"require-ack"`
+	// Timeout of each attempt, connection and handshake included, 10s by default.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize is the maximum number of entries per message, 512 by default.
+	BatchSize int `yaml:"batch-size"`
+	// BatchTimeout is the maximum delay of an entry before its batch is sent, 1s by default.
+	BatchTimeout time.Duration `yaml:"batch-timeout"`
+	// QueueSize is the maximum number of entries waiting for a batch, 2048 by default. Entries above it are dropped.
+	QueueSize int `yaml:"queue-size"`
+	// Retry is the backoff of the batches failed on connection errors or missing acknowledgements.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+func (c FluentdConfig) withDefaults() FluentdConfig {
+	if c.Address == "" {
+		c.Address = "localhost:24224"
+	}
+	if c.Tag == "" {
+		c.Tag = "logzum"
+	}
+	if c.Hostname == "" {
+		c.Hostname = defaultHostname()
+	}
+	return c
+}
+
+func (c FluentdConfig) batch() batchConfig {
+	return batchConfig{size: c.BatchSize, timeout: c.BatchTimeout, queueSize: c.QueueSize}
+}
+
+func (c FluentdConfig) problems() []string {
+	var problems []string
+	if c.Address != "" {
+		if _, port, err := net.SplitHostPort(c.Address); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("fluentd address %q must be in the host:port form", c.Address))
+		}
+	}
+	if c.Username != "" && c.SharedKey == "" {
+		problems = append(problems, "fluentd username requires a shared-key")
+	}
+	return append(problems, batchProblems("fluentd", c.Timeout, c.batch(), c.Retry)...)
+}
+
+// fluentdSink is the sink forwarding batches of entries to Fluentd over a connection kept between batches.
+type fluentdSink struct {
+	*batcher
+	config FluentdConfig
+	fields map[string]interface{}
+
+	// conn and dec are only used by the batcher goroutine, until close
+	conn net.Conn
+	dec  *msgpack.Decoder
+}
+
+// newFluentdSink starts a sink, with fields added to all the records.
+func newFluentdSink(config FluentdConfig, fields map[string]interface{}, counters *counters) (*fluentdSink, error) {
+	s := &fluentdSink{
+		config: config.withDefaults(),
+		fields: fields,
+	}
+	s.batcher = newBatcher("Fluentd", config.batch(), config.Retry, config.Timeout, counters, s.send)
+	return s, nil
+}
+
+func (s *fluentdSink) add(entry *logrus.Entry) {
+	for key, value := range s.fields {
+		entry.Data[key] = value
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	s.batcher.add(entry)
+}
+
+func (s *fluentdSink) close() error {
+	s.batcher.close()
+	s.disconnect()
+	return nil
+}
+
+func (s *fluentdSink) endpoint() string {
+	return s.config.Address
+}
+
+func (s *fluentdSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.dec = nil, nil
+	}
+}
+
+// fluentdRecord converts the fields to values encoded by msgpack as Fluentd expects them.
+func fluentdRecord(entry *logrus.Entry) map[string]interface{} {
+	record := make(map[string]interface{}, len(entry.Data)+2)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		case time.Duration:
+			value = v.String()
+		}
+		record[key] = value
+	}
+	record["level"] = entry.Level.String()
+	record["message"] = entry.Message
+	return record
+}
+
+// appendEntry encodes the [time, record] entry, with the time as the EventTime extension of nanosecond precision.
+func appendEntry(buf *bytes.Buffer, entry *logrus.Entry) error {
+	enc := msgpack.NewEncoder(buf)
+	enc.EncodeArrayLen(2)
+	enc.EncodeExtHeader(0, 8)
+	var eventTime [8]byte
+	binary.BigEndian.PutUint32(eventTime[:4], uint32(entry.Time.Unix()))
+	binary.BigEndian.PutUint32(eventTime[4:], uint32(entry.Time.Nanosecond()))
+	buf.Write(eventTime[:])
+	return enc.Encode(fluentdRecord(entry))
+}
+
+func (s *fluentdSink) send(ctx context.Context, entries []*logrus.Entry) (int, error) {
+	var packed bytes.Buffer
+	rejected, size := 0, 0
+	for _, entry := range entries {
+		n := packed.Len()
+		if err := appendEntry(&packed, entry); err != nil {
+			log.Printf("BurzumLogs: error on format %v\n", err)
+			packed.Truncate(n)
+			rejected++
+			continue
+		}
+		size++
+	}
+	if size == 0 {
+		return rejected, nil
+	}
+
+	payload := packed.Bytes()
+	option := map[string]interface{}{"size": size}
+	if !s.config.DisableCompression {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		w.Write(payload)
+		if err := w.Close(); err != nil {
+			return 0, err
+		}
+		payload = compressed.Bytes()
+		option["compressed"] = "gzip"
+	}
+	var chunk string
+	if s.config.RequireAck {
+		id := make([]byte, 16)
+		rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+
+	var message bytes.Buffer
+	enc := msgpack.NewEncoder(&message)
+	enc.EncodeArrayLen(3)
+	enc.EncodeString(s.config.Tag)
+	enc.EncodeBytes(payload)
+	if err := enc.Encode(option); err != nil {
+		return 0, err
+	}
+
+	if err := s.connect(ctx); err != nil {
+		if err == ErrFluentdAuth {
+			return 0, err
+		}
+		return 0, retryableError{err: err}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetDeadline(deadline)
+	}
+	if _, err := s.conn.Write(message.Bytes()); err != nil {
+		s.disconnect()
+		return 0, retryableError{err: err}
+	}
+	if s.config.RequireAck {
+		var ack struct {
+			Ack string `msgpack:"ack"`
+		}
+		if err := s.dec.Decode(&ack); err != nil {
+			s.disconnect()
+			return 0, retryableError{err: fmt.Errorf("logzum: no fluentd ack: %v", err)}
+		}
+		if ack.Ack != chunk {
+			s.disconnect()
+			return 0, retryableError{err: fmt.Errorf("logzum: fluentd acked %q, expected %q", ack.Ack, chunk)}
+		}
+	}
+	return rejected, nil
+}
+
+// connect dials Fluentd and does the handshake when there is no connection.
+func (s *fluentdSink) connect(ctx context.Context) error {
+	if s.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{KeepAlive: DefaultConfig.KeepAlivePeriod}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	var conn net.Conn
+	var err error
+	if s.config.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.config.Address, s.config.TLSConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.config.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to connect, error: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	s.conn, s.dec = conn, msgpack.NewDecoder(conn)
+	if s.config.SharedKey != "" {
+		if err := s.handshake(); err != nil {
+			s.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+// handshake answers the HELO of Fluentd with a PING authenticated by the shared key, and checks its PONG.
+func (s *fluentdSink) handshake() error {
+	var helo []interface{}
+	if err := s.dec.Decode(&helo); err != nil {
+		return fmt.Errorf("logzum: no fluentd HELO: %v", err)
+	}
+	if len(helo) != 2 || helo[0] != "HELO" {
+		return fmt.Errorf("logzum: expected fluentd HELO, got %v", helo)
+	}
+	options, _ := helo[1].(map[string]interface{})
+	nonce := msgpackString(options["nonce"])
+	authSalt := msgpackString(options["auth"])
+
+	saltBytes := make([]byte, 16)
+	rand.Read(saltBytes)
+	salt := hex.EncodeToString(saltBytes)
+
+	username, passwordDigest := "", ""
+	if authSalt != "" {
+		username, passwordDigest = s.config.Username, sha512Hex(authSalt, s.config.Username, s.config.Password)
+	}
+	var ping bytes.Buffer
+	err := msgpack.NewEncoder(&ping).Encode([]string{
+		"PING", s.config.Hostname, salt, sha512Hex(salt, s.config.Hostname, nonce, s.config.SharedKey), username, passwordDigest,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(ping.Bytes()); err != nil {
+		return err
+	}
+
+	var pong []interface{}
+	if err := s.dec.Decode(&pong); err != nil {
+		return fmt.Errorf("logzum: no fluentd PONG: %v", err)
+	}
+	if len(pong) != 5 || pong[0] != "PONG" {
+		return fmt.Errorf("logzum: expected fluentd PONG, got %v", pong)
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		log.Printf("BurzumLogs: fluentd refused the handshake: %s\n", msgpackString(pong[2]))
+		return ErrFluentdAuth
+	}
+	if msgpackString(pong[4]) != sha512Hex(salt, msgpackString(pong[3]), nonce, s.config.SharedKey) {
+		log.Printf("BurzumLogs: fluentd answered an invalid shared key digest\n")
+		return ErrFluentdAuth
+	}
+	return nil
+}
+
+// msgpackString returns the str or bin value v as a string.
+func msgpackString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return ""
+}
+
+func sha512Hex(parts ...string) string {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
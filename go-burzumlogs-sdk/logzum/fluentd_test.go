@@ -0,0 +1,322 @@
+package logzum_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+type forwardEntry struct {
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+type forwardMessage struct {
+	Tag     string
+	Entries []forwardEntry
+	Option  map[string]interface{}
+}
+
+// fakeFluentd is a forward input, with the shared key handshake when sharedKey is set. It skips the acks of the
+// first dropAcks chunks.
+type fakeFluentd struct {
+	t         *testing.T
+	listener  net.Listener
+	sharedKey string
+	username  string
+	password  string
+
+	mu       sync.Mutex
+	dropAcks int
+
+	messages chan forwardMessage
+}
+
+func newFakeFluentd(t *testing.T, configure func(f *fakeFluentd)) *fakeFluentd {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	f := &fakeFluentd{t: t, listener: l, messages: make(chan forwardMessage, 100)}
+	if configure != nil {
+		configure(f)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeFluentd) Close() {
+	f.listener.Close()
+}
+
+func digest(parts ...string) string {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (f *fakeFluentd) write(conn net.Conn, v interface{}) {
+	b, err := msgpack.Marshal(v)
+	require.NoError(f.t, err)
+	conn.Write(b)
+}
+
+func (f *fakeFluentd) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := msgpack.NewDecoder(conn)
+
+	if f.sharedKey != "" {
+		nonce, authSalt := "nonce", ""
+		if f.username != "" {
+			authSalt = "auth-salt"
+		}
+		f.write(conn, []interface{}{"HELO", map[string]interface{}{"nonce": []byte(nonce), "auth": []byte(authSalt), "keepalive": true}})
+
+		var ping []string
+		if err := dec.Decode(&ping); err != nil || len(ping) != 6 || ping[0] != "PING" {
+			return
+		}
+		hostname, salt := ping[1], ping[2]
+		ok := ping[3] == digest(salt, hostname, nonce, f.sharedKey)
+		if authSalt != "" {
+			ok = ok && ping[4] == f.username && ping[5] == digest(authSalt, f.username, f.password)
+		}
+		reason := ""
+		if !ok {
+			reason = "shared key mismatch"
+		}
+		f.write(conn, []interface{}{"PONG", ok, reason, "fluentd-1", digest(salt, "fluentd-1", nonce, f.sharedKey)})
+		if !ok {
+			return
+		}
+	}
+
+	for {
+		if _, err := dec.DecodeArrayLen(); err != nil {
+			return
+		}
+		var message forwardMessage
+		var packed []byte
+		require.NoError(f.t, dec.Decode(&message.Tag))
+		require.NoError(f.t, dec.Decode(&packed))
+		require.NoError(f.t, dec.Decode(&message.Option))
+
+		if message.Option["compressed"] == "gzip" {
+			r, err := gzip.NewReader(bytes.NewReader(packed))
+			require.NoError(f.t, err)
+			packed, err = ioutil.ReadAll(r)
+			require.NoError(f.t, err)
+		}
+		message.Entries = decodeForwardEntries(f.t, packed)
+		f.messages <- message
+
+		if chunk, ok := message.Option["chunk"]; ok {
+			f.mu.Lock()
+			drop := f.dropAcks > 0
+			f.dropAcks--
+			f.mu.Unlock()
+			if !drop {
+				f.write(conn, map[string]interface{}{"ack": chunk})
+			}
+		}
+	}
+}
+
+func decodeForwardEntries(t *testing.T, packed []byte) []forwardEntry {
+	var entries []forwardEntry
+	r := bytes.NewReader(packed)
+	dec := msgpack.NewDecoder(r)
+	for r.Len() > 0 {
+		n, err := dec.DecodeArrayLen()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		id, length, err := dec.DecodeExtHeader()
+		require.NoError(t, err)
+		require.Equal(t, int8(0), id, "EventTime")
+		require.Equal(t, 8, length)
+		var eventTime [8]byte
+		_, err = io.ReadFull(r, eventTime[:])
+		require.NoError(t, err)
+
+		var entry forwardEntry
+		entry.Time = time.Unix(int64(binary.BigEndian.Uint32(eventTime[:4])), int64(binary.BigEndian.Uint32(eventTime[4:])))
+		require.NoError(t, dec.Decode(&entry.Record))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func receiveForward(t *testing.T, messages <-chan forwardMessage) forwardMessage {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a forward message")
+		return forwardMessage{}
+	}
+}
+
+func newFluentdHook(t *testing.T, fluentd logzum.FluentdConfig) logzum.Hook {
+	if fluentd.BatchTimeout == 0 {
+		fluentd.BatchTimeout = time.Hour
+	}
+	fluentd.Retry.InitialInterval = 10 * time.Millisecond
+
+	config := logzum.DefaultConfig
+	config.Fields = map[string]interface{}{"service": "checkout"}
+	config.Fluentd = &fluentd
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h
+}
+
+func TestFluentdForward(t *testing.T) {
+	fluentd := newFakeFluentd(t, nil)
+	defer fluentd.Close()
+
+	h := newFluentdHook(t, logzum.FluentdConfig{Address: fluentd.listener.Addr().String(), Tag: "app.checkout"})
+	defer h.Close()
+
+	now := time.Date(2019, 10, 1, 10, 0, 0, 123456789, time.UTC)
+	require.NoError(t, h.Fire(&logrus.Entry{
+		Time:    now,
+		Level:   logrus.ErrorLevel,
+		Message: "payment declined",
+		Data:    logrus.Fields{"error": errors.New("boom"), "attempt": 2},
+	}))
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "second", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	message := receiveForward(t, fluentd.messages)
+	assert.Equal(t, "app.checkout", message.Tag)
+	assert.EqualValues(t, 2, message.Option["size"])
+	assert.Equal(t, "gzip", message.Option["compressed"])
+	assert.NotContains(t, message.Option, "chunk")
+
+	require.Len(t, message.Entries, 2)
+	assert.True(t, now.Equal(message.Entries[0].Time), "nanosecond EventTime")
+	assert.Equal(t, "payment declined", message.Entries[0].Record["message"])
+	assert.Equal(t, "error", message.Entries[0].Record["level"])
+	assert.Equal(t, "boom", message.Entries[0].Record["error"])
+	assert.EqualValues(t, 2, message.Entries[0].Record["attempt"])
+	assert.Equal(t, "checkout", message.Entries[0].Record["service"])
+	assert.False(t, message.Entries[1].Time.IsZero(), "entries without time are stamped when received")
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, fluentd.listener.Addr().String(), stats.Host)
+}
+
+func TestFluentdUncompressed(t *testing.T) {
+	fluentd := newFakeFluentd(t, nil)
+	defer fluentd.Close()
+
+	h := newFluentdHook(t, logzum.FluentdConfig{Address: fluentd.listener.Addr().String(), DisableCompression: true})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "plain", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	message := receiveForward(t, fluentd.messages)
+	assert.Equal(t, "logzum", message.Tag)
+	assert.NotContains(t, message.Option, "compressed")
+	assert.Equal(t, "plain", message.Entries[0].Record["message"])
+}
+
+func TestFluentdAck(t *testing.T) {
+	fluentd := newFakeFluentd(t, func(f *fakeFluentd) { f.dropAcks = 1 })
+	defer fluentd.Close()
+
+	h := newFluentdHook(t, logzum.FluentdConfig{
+		Address:    fluentd.listener.Addr().String(),
+		RequireAck: true,
+		Timeout:    200 * time.Millisecond,
+	})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "at least once", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	first := receiveForward(t, fluentd.messages)
+	assert.NotEmpty(t, first.Option["chunk"])
+	second := receiveForward(t, fluentd.messages)
+	assert.Equal(t, first.Entries, second.Entries, "the batch is sent again when the ack is missing")
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(1), stats.Sent)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestFluentdSharedKey(t *testing.T) {
+	fluentd := newFakeFluentd(t, func(f *fakeFluentd) {
+		f.sharedKey, f.username, f.password = "secret", "app", "p4ss"
+	})
+	defer fluentd.Close()
+
+	h := newFluentdHook(t, logzum.FluentdConfig{
+		Address:   fluentd.listener.Addr().String(),
+		SharedKey: "secret",
+		Username:  "app",
+		Password:  "p4ss",
+	})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "authenticated", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Equal(t, "authenticated", receiveForward(t, fluentd.messages).Entries[0].Record["message"])
+
+	refused := newFluentdHook(t, logzum.FluentdConfig{
+		Address:   fluentd.listener.Addr().String(),
+		SharedKey: "wrong",
+	})
+	defer refused.Close()
+
+	require.NoError(t, refused.Fire(&logrus.Entry{Message: "refused", Data: logrus.Fields{}}))
+	require.NoError(t, refused.Flush(2*time.Second))
+	assert.Equal(t, uint64(1), refused.Stats().Failed, "authentication failures are not retried")
+	assert.Empty(t, fluentd.messages)
+}
+
+func TestFluentdConfig(t *testing.T) {
+	os.Setenv("BURZUM_FLUENTD_ADDRESS", "fluent-bit:24224")
+	defer os.Unsetenv("BURZUM_FLUENTD_ADDRESS")
+	os.Setenv("BURZUM_FLUENTD_SHARED_KEY", "secret")
+	defer os.Unsetenv("BURZUM_FLUENTD_SHARED_KEY")
+
+	config, err := logzum.ConfigFromEnv("BURZUM")
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.FluentdConfig{Address: "fluent-bit:24224", SharedKey: "secret"}, config.Fluentd)
+
+	err = logzum.Config{Fluentd: &logzum.FluentdConfig{Address: "fluent-bit", Username: "app"}, MaxRetries: 1}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `fluentd address "fluent-bit" must be in the host:port form`)
+		assert.Contains(t, err.Error(), "fluentd username requires a shared-key")
+		assert.NotContains(t, err.Error(), "formatter")
+	}
+}
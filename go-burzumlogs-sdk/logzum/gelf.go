@@ -0,0 +1,256 @@
+package logzum
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultGELFChunkSize is the largest UDP datagram sent by GELFUDPTransport, fitting the usual MTU.
+	DefaultGELFChunkSize = 1420
+
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+var (
+	// ErrGELFTooLarge is returned by the GELF UDP transport for messages that need more than 128 chunks.
+	ErrGELFTooLarge = errors.New("logzum: GELF message needs more than 128 chunks")
+
+	gelfChunkMagic = []byte{0x1e, 0x0f}
+
+	gelfInvalidField = regexp.MustCompile(`[^\w.\-]`)
+
+	hostname     string
+	hostnameOnce sync.Once
+)
+
+// SyslogSeverity maps level to its syslog severity, from 1 (alert) for panic to 7 (debug) for debug and trace.
+func SyslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// GELFFormatter formats the entries as GELF 1.1 messages.
+//
+// The first line of the message is the short_message, and the whole message is the full_message when it has more
+// than one line or is longer than ShortMessageLength. The fields are added with a `_` prefix, the characters not
+// allowed in a GELF field name are replaced by `_` and the reserved `id` field is renamed to `_id_`.
+type GELFFormatter struct {
+	// Host is the source of the messages, the hostname by default.
+	Host string
+	// ShortMessageLength cuts the short_message to that many bytes, unlimited if zero.
+	ShortMessageLength int
+}
+
+func (f *GELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	host := f.Host
+	if host == "" {
+		host = defaultHostname()
+	}
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	short, full := splitMessage(entry.Message, f.ShortMessageLength)
+	message := make(map[string]interface{}, len(entry.Data)+6)
+	for k, v := range entry.Data {
+		message[gelfFieldName(k)] = gelfValue(v)
+	}
+	message["version"] = "1.1"
+	message["host"] = host
+	message["short_message"] = short
+	message["timestamp"] = float64(t.UnixNano()/int64(time.Millisecond)) / 1e3
+	message["level"] = SyslogSeverity(entry.Level)
+	if full != "" {
+		message["full_message"] = full
+	}
+
+	serialized, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal GELF message, %v", err)
+	}
+	return serialized, nil
+}
+
+// splitMessage returns the short_message and, when it doesn't hold the whole message, the full_message.
+func splitMessage(msg string, length int) (short, full string) {
+	short = strings.TrimSpace(msg)
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = strings.TrimSpace(short[:i])
+	}
+	if length > 0 && len(short) > length {
+		short = short[:length]
+	}
+	if short == "" {
+		// short_message is required
+		short = "-"
+	}
+	if short != strings.TrimSpace(msg) {
+		full = msg
+	}
+	return short, full
+}
+
+func gelfFieldName(key string) string {
+	if key == "id" {
+		return "_id_"
+	}
+	return "_" + gelfInvalidField.ReplaceAllString(key, "_")
+}
+
+// gelfValue converts v to a string or a number, the only values allowed by GELF.
+func gelfValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	case nil:
+		return ""
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func defaultHostname() string {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "localhost"
+		}
+	})
+	return hostname
+}
+
+// GELFUDPTransport sends each message in a UDP datagram, compressed with zlib, split in chunks when it is larger
+// than ChunkSize.
+type GELFUDPTransport struct {
+	// ChunkSize is the largest datagram sent, DefaultGELFChunkSize if zero.
+	ChunkSize int
+	// DisableCompression sends the messages uncompressed.
+	DisableCompression bool
+}
+
+func (t GELFUDPTransport) Dial(config Config) (io.WriteCloser, error) {
+	conn, err := net.Dial("udp", config.Host)
+	if err != nil {
+		return nil, err
+	}
+	size := t.ChunkSize
+	if size <= gelfChunkHeaderSize {
+		size = DefaultGELFChunkSize
+	}
+	return &gelfUDPConn{Conn: conn, chunkSize: size, compress: !t.DisableCompression}, nil
+}
+
+type gelfUDPConn struct {
+	net.Conn
+
+	chunkSize int
+
+	compress bool
+}
+
+func (c *gelfUDPConn) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n\x00")
+	if c.compress {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		w.Write(msg)
+		if err := w.Close(); err != nil {
+			return 0, err
+		}
+		msg = b.Bytes()
+	}
+
+	if len(msg) <= c.chunkSize {
+		if _, err := c.Conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	size := c.chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > gelfMaxChunks {
+		return 0, ErrGELFTooLarge
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return 0, err
+	}
+
+	chunk := make([]byte, 0, c.chunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk[:0], gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*size:end]...)
+		if _, err := c.Conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// GELFTCPTransport sends the messages uncompressed over TCP, each one terminated by a null byte.
+type GELFTCPTransport struct{}
+
+func (GELFTCPTransport) Dial(config Config) (io.WriteCloser, error) {
+	conn, err := dialTCP(config)
+	if err != nil {
+		return nil, err
+	}
+	return &gelfTCPConn{conn}, nil
+}
+
+type gelfTCPConn struct {
+	*net.TCPConn
+}
+
+func (c *gelfTCPConn) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n\x00")
+	framed := make([]byte, len(msg)+1)
+	copy(framed, msg)
+	if _, err := c.TCPConn.Write(framed); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
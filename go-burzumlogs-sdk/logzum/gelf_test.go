@@ -0,0 +1,221 @@
+package logzum_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+func TestGELFFormatter(t *testing.T) {
+	f := &logzum.GELFFormatter{Host: "api-1"}
+	serialized, err := f.Format(&logrus.Entry{
+		Time:    time.Date(2019, 10, 1, 10, 0, 0, 123456789, time.UTC),
+		Level:   logrus.WarnLevel,
+		Message: "request failed\nstack trace",
+		Data: logrus.Fields{
+			"id":          42,
+			"http.status": 502,
+			"user name":   "ana",
+			"error":       errors.New("boom"),
+			"ok":          false,
+			"labels":      map[string]string{"team": "payments"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": "1.1",
+		"host": "api-1",
+		"short_message": "request failed",
+		"full_message": "request failed\nstack trace",
+		"timestamp": 1569924000.123,
+		"level": 4,
+		"_id_": 42,
+		"_http.status": 502,
+		"_user_name": "ana",
+		"_error": "boom",
+		"_ok": "false",
+		"_labels": "{\"team\":\"payments\"}"
+	}`, string(serialized))
+
+	f = &logzum.GELFFormatter{ShortMessageLength: 5}
+	serialized, err = f.Format(&logrus.Entry{Level: logrus.InfoLevel, Message: "short enough", Data: logrus.Fields{}})
+	require.NoError(t, err)
+	message := decode(t, serialized)
+	assert.Equal(t, "short", message["short_message"])
+	assert.Equal(t, "short enough", message["full_message"])
+	assert.NotEmpty(t, message["host"], "the hostname by default")
+
+	serialized, err = f.Format(&logrus.Entry{Level: logrus.ErrorLevel, Message: "ok", Data: logrus.Fields{}})
+	require.NoError(t, err)
+	message = decode(t, serialized)
+	assert.Equal(t, "ok", message["short_message"])
+	assert.NotContains(t, message, "full_message")
+	assert.Equal(t, float64(3), message["level"])
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	for level, severity := range map[logrus.Level]int{
+		logrus.PanicLevel: 1,
+		logrus.FatalLevel: 2,
+		logrus.ErrorLevel: 3,
+		logrus.WarnLevel:  4,
+		logrus.InfoLevel:  6,
+		logrus.DebugLevel: 7,
+	} {
+		assert.Equal(t, severity, logzum.SyslogSeverity(level), level.String())
+	}
+}
+
+// readGELFUDP reassembles the next message received by conn, decompressing it.
+func readGELFUDP(t *testing.T, conn net.PacketConn) map[string]interface{} {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	chunks := map[byte][]byte{}
+	var count byte = 1
+	for byte(len(chunks)) < count {
+		buf := make([]byte, 65536)
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		buf = buf[:n]
+		if !bytes.HasPrefix(buf, []byte{0x1e, 0x0f}) {
+			chunks[0] = buf
+			break
+		}
+		require.True(t, n <= 100, "chunks must fit ChunkSize")
+		count = buf[11]
+		chunks[buf[10]] = buf[12:]
+	}
+
+	var compressed []byte
+	for i := byte(0); i < count; i++ {
+		compressed = append(compressed, chunks[i]...)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return decode(t, raw)
+}
+
+func TestGELFUDPTransport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := logzum.DefaultConfig
+	config.Host = conn.LocalAddr().String()
+	config.Formatter = &logzum.GELFFormatter{Host: "api-1"}
+	config.Transport = logzum.GELFUDPTransport{ChunkSize: 100}
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	defer h.Close()
+
+	h.Fire(&logrus.Entry{Message: "small", Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	message := readGELFUDP(t, conn)
+	assert.Equal(t, "small", message["short_message"])
+	assert.NotContains(t, message, "_bztoken", "the token is sent to Burzum only")
+
+	// random, so compression doesn't make it fit a single datagram
+	large := make([]byte, 600)
+	for i := range large {
+		large[i] = byte('a' + rand.Intn(26))
+	}
+	h.Fire(&logrus.Entry{Message: "large", Level: logrus.InfoLevel, Data: logrus.Fields{"payload": string(large)}})
+	message = readGELFUDP(t, conn)
+	assert.Equal(t, "large", message["short_message"])
+	assert.Equal(t, string(large), message["_payload"])
+}
+
+func TestGELFUDPTransportTooLarge(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := logzum.DefaultConfig
+	config.Host = conn.LocalAddr().String()
+	w, err := logzum.GELFUDPTransport{ChunkSize: 20, DisableCompression: true}.Dial(config)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write(bytes.Repeat([]byte("x"), 8*128+1))
+	assert.Equal(t, logzum.ErrGELFTooLarge, err)
+}
+
+func TestGELFTCPTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+
+	config := logzum.DefaultConfig
+	config.Host = l.Addr().String()
+	config.Formatter = &logzum.GELFFormatter{}
+	config.Transport = logzum.GELFTCPTransport{}
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	defer h.Close()
+
+	h.Fire(&logrus.Entry{Message: "one\ntwo", Level: logrus.ErrorLevel, Data: logrus.Fields{}})
+	h.Fire(&logrus.Entry{Message: "three", Level: logrus.InfoLevel, Data: logrus.Fields{}})
+
+	for _, expected := range []string{"one", "three"} {
+		select {
+		case msg := <-messages:
+			require.True(t, strings.HasSuffix(msg, "\x00"))
+			assert.NotContains(t, msg, "}\n", "the messages are framed by the null byte only")
+			assert.Equal(t, expected, decode(t, []byte(strings.TrimSuffix(msg, "\x00")))["short_message"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a GELF message")
+		}
+	}
+}
+
+func TestReconfigureTransport(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) {})
+	defer h.Close()
+
+	h.Fire(&logrus.Entry{Message: "tcp", Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	assert.Equal(t, "tcp", receive(t, lines)["message"])
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := logzum.DefaultConfig
+	config.Host = conn.LocalAddr().String()
+	config.Formatter = &logzum.GELFFormatter{}
+	config.Transport = logzum.GELFUDPTransport{}
+	require.NoError(t, h.Reconfigure(config))
+
+	h.Fire(&logrus.Entry{Message: "udp", Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	assert.Equal(t, "udp", readGELFUDP(t, conn)["short_message"])
+}
@@ -0,0 +1,259 @@
+package logzum
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/renstrom/shortuuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// TruncatedFieldsField is the log field listing the fields truncated to fit the size limits.
+	TruncatedFieldsField = "truncated_fields"
+	// ChunkIDField is the log field shared by the chunks of a split message.
+	ChunkIDField = "chunk.id"
+	// ChunkIndexField is the log field with the position of a chunk, starting at zero.
+	ChunkIndexField = "chunk.index"
+	// ChunkCountField is the log field with the number of chunks of a split message.
+	ChunkCountField = "chunk.count"
+)
+
+// truncate keeps the first max bytes of s, on a rune boundary, followed by a marker with the number of bytes removed.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("…[truncated %d bytes]", len(s)-cut)
+}
+
+// cut returns the longest prefix of s not exceeding max bytes, on a rune boundary.
+func cut(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// limiter enforces the MaxFieldBytes and MaxEntryBytes of an entry while formatting it.
+type limiter struct {
+	config Config
+
+	entry *logrus.Entry
+
+	// originals holds the fields that can be truncated as strings, with their limit when truncated
+	originals map[string]string
+	limits    map[string]int
+}
+
+// format serializes entry in one or more lines within the configured limits.
+func (p *pipeline) format(entry *logrus.Entry) ([][]byte, error) {
+	if p.config.MaxFieldBytes <= 0 && p.config.MaxEntryBytes <= 0 {
+		serialized, err := p.config.Formatter.Format(entry)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{serialized}, nil
+	}
+
+	return p.newLimiter(entry).format()
+}
+
+// limitFields truncates the text fields larger than MaxFieldBytes of the entries handed to a sink, as they are not
+// formatted in lines MaxEntryBytes could apply to.
+func (p *pipeline) limitFields(entry *logrus.Entry) {
+	max := p.config.MaxFieldBytes
+	if max <= 0 {
+		return
+	}
+	l := p.newLimiter(entry)
+	for key, s := range l.originals {
+		if len(s) > max {
+			l.truncate(key, max)
+		}
+	}
+}
+
+func (p *pipeline) newLimiter(entry *logrus.Entry) *limiter {
+	l := &limiter{
+		config:    p.config,
+		entry:     entry,
+		originals: make(map[string]string),
+		limits:    make(map[string]int),
+	}
+	for key, value := range entry.Data {
+		if s, ok := truncatable(value); ok && key != "bztoken" {
+			l.originals[key] = s
+		}
+	}
+	return l
+}
+
+// truncatable returns the text of the field values that can be truncated, as WithError stack traces.
+func truncatable(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case error:
+		return v.Error(), true
+	case time.Time, time.Duration:
+		return "", false
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+	return "", false
+}
+
+func (l *limiter) format() ([][]byte, error) {
+	if max := l.config.MaxFieldBytes; max > 0 {
+		for key, s := range l.originals {
+			if len(s) > max {
+				l.truncate(key, max)
+			}
+		}
+	}
+
+	serialized, err := l.config.Formatter.Format(l.entry)
+	if err != nil {
+		return nil, err
+	}
+	max := l.config.MaxEntryBytes
+	if max <= 0 {
+		return [][]byte{serialized}, nil
+	}
+
+	// cut the largest fields, while they are larger than the message
+	for excess := len(serialized) - max; excess > 0; excess = len(serialized) - max {
+		key, size := l.largest()
+		if size <= len(l.entry.Message) {
+			break
+		}
+		limit := size - excess
+		if limit < len(l.entry.Message) {
+			limit = len(l.entry.Message)
+		}
+		l.truncate(key, limit)
+		if serialized, err = l.config.Formatter.Format(l.entry); err != nil {
+			return nil, err
+		}
+	}
+	if len(serialized) <= max {
+		return [][]byte{serialized}, nil
+	}
+	return l.split()
+}
+
+func (l *limiter) truncate(key string, limit int) {
+	l.limits[key] = limit
+	l.entry.Data[key] = truncate(l.originals[key], limit)
+
+	truncated := make([]string, 0, len(l.limits))
+	for key := range l.limits {
+		truncated = append(truncated, key)
+	}
+	sort.Strings(truncated)
+	l.entry.Data[TruncatedFieldsField] = truncated
+}
+
+// largest returns the string field with the most bytes left.
+func (l *limiter) largest() (string, int) {
+	var largest string
+	size := -1
+	for key, s := range l.originals {
+		n := len(s)
+		if limit, ok := l.limits[key]; ok {
+			n = limit
+		}
+		if n > size || (n == size && key < largest) {
+			largest, size = key, n
+		}
+	}
+	return largest, size
+}
+
+// split sends the message in chunks, each one within MaxEntryBytes.
+func (l *limiter) split() ([][]byte, error) {
+	max := l.config.MaxEntryBytes
+	message := l.entry.Message
+	defer func() { l.entry.Message = message }()
+
+	l.entry.Data[ChunkIDField] = shortuuid.New()
+	// the largest possible index and count, so the chunks only get smaller when the actual ones are set
+	l.entry.Data[ChunkIndexField] = len(message)
+	l.entry.Data[ChunkCountField] = len(message)
+
+	l.entry.Message = ""
+	base, err := l.config.Formatter.Format(l.entry)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) >= max {
+		log.Printf("BurzumLogs: entry exceeds %d bytes even without its message, sending it whole", max)
+		l.entry.Message = message
+		for _, key := range []string{ChunkIDField, ChunkIndexField, ChunkCountField} {
+			delete(l.entry.Data, key)
+		}
+		serialized, err := l.config.Formatter.Format(l.entry)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{serialized}, nil
+	}
+
+	var chunks []string
+	for rest := message; rest != ""; {
+		size := max - len(base)
+		for {
+			l.entry.Message = cut(rest, size)
+			serialized, err := l.config.Formatter.Format(l.entry)
+			if err != nil {
+				return nil, err
+			}
+			// escaped characters take more room than their bytes in the message
+			if over := len(serialized) - max; over > 0 && size > 1 {
+				size -= over
+				if size < 1 {
+					size = 1
+				}
+				continue
+			}
+			break
+		}
+		if l.entry.Message == "" {
+			// a single rune larger than the room left
+			_, n := utf8.DecodeRuneInString(rest)
+			l.entry.Message = rest[:n]
+		}
+		chunks = append(chunks, l.entry.Message)
+		rest = rest[len(l.entry.Message):]
+	}
+
+	lines := make([][]byte, 0, len(chunks))
+	l.entry.Data[ChunkCountField] = len(chunks)
+	for i, chunk := range chunks {
+		l.entry.Message = chunk
+		l.entry.Data[ChunkIndexField] = i
+		serialized, err := l.config.Formatter.Format(l.entry)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, serialized)
+	}
+	return lines, nil
+}
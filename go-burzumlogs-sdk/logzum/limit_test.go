@@ -0,0 +1,117 @@
+package logzum_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedHook(t *testing.T, maxEntryBytes, maxFieldBytes int) (logrus.Hook, <-chan []byte) {
+	return newTestHook(t, func(config *logzum.Config) {
+		config.MaxEntryBytes = maxEntryBytes
+		config.MaxFieldBytes = maxFieldBytes
+	})
+}
+
+func TestMaxFieldBytes(t *testing.T) {
+	h, lines := newLimitedHook(t, 0, 10)
+
+	data := logrus.Fields{"body": strings.Repeat("a", 30), "name": "éééééé", "short": "ok", "count": 12345678901}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: strings.Repeat("m", 50), Data: data, Level: logrus.InfoLevel}))
+
+	line := receive(t, lines)
+	assert.Equal(t, "aaaaaaaaaa…[truncated 20 bytes]", line["body"])
+	assert.Equal(t, "ééééé…[truncated 2 bytes]", line["name"])
+	assert.Equal(t, "ok", line["short"])
+	assert.Equal(t, strings.Repeat("m", 50), line["message"])
+	assert.Equal(t, []interface{}{"body", "name"}, line[logzum.TruncatedFieldsField])
+	assert.Equal(t, strings.Repeat("a", 30), data["body"], "the logged fields must not be changed")
+}
+
+func TestMaxFieldBytesNonStrings(t *testing.T) {
+	h, lines := newLimitedHook(t, 0, 10)
+
+	stack := errors.New(strings.Repeat("s", 30))
+	data := logrus.Fields{
+		logrus.ErrorKey: stack,
+		"payload":       []byte(strings.Repeat("b", 30)),
+		"document":      document(strings.Repeat("d", 30)),
+		"at":            time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "failed", Data: data, Level: logrus.ErrorLevel}))
+
+	line := receive(t, lines)
+	assert.Equal(t, "ssssssssss…[truncated 20 bytes]", line[logrus.ErrorKey])
+	assert.Equal(t, "bbbbbbbbbb…[truncated 20 bytes]", line["payload"])
+	assert.Equal(t, "dddddddddd…[truncated 20 bytes]", line["document"])
+	assert.Equal(t, "2019-10-01T10:00:00Z", line["at"], "times are not truncated")
+	assert.Equal(t, []interface{}{"document", "error", "payload"}, line[logzum.TruncatedFieldsField])
+	assert.Equal(t, stack, data[logrus.ErrorKey], "the logged fields must not be changed")
+}
+
+func TestMaxEntryBytesTruncatesLargestField(t *testing.T) {
+	h, lines := newLimitedHook(t, 500, 0)
+
+	data := logrus.Fields{"body": strings.Repeat("b", 2000), "stack": strings.Repeat("s", 300), "id": "1"}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "request failed", Data: data, Level: logrus.ErrorLevel}))
+
+	line := receiveLimited(t, lines, 500)
+	assert.Contains(t, line["body"], "…[truncated")
+	assert.Equal(t, "1", line["id"])
+	assert.Equal(t, "request failed", line["message"])
+	assert.Contains(t, line[logzum.TruncatedFieldsField], "body")
+}
+
+func TestMaxEntryBytesSplitsMessage(t *testing.T) {
+	h, lines := newLimitedHook(t, 300, 0)
+
+	message := strings.Repeat(`"quoted" ação `, 100)
+	require.NoError(t, h.Fire(&logrus.Entry{Message: message, Data: logrus.Fields{"id": "1"}, Level: logrus.InfoLevel}))
+
+	first := receiveLimited(t, lines, 300)
+	count := int(first[logzum.ChunkCountField].(float64))
+	require.True(t, count > 1)
+
+	chunks := []string{first["message"].(string)}
+	assert.Equal(t, float64(0), first[logzum.ChunkIndexField])
+	for i := 1; i < count; i++ {
+		line := receiveLimited(t, lines, 300)
+		assert.Equal(t, first[logzum.ChunkIDField], line[logzum.ChunkIDField])
+		assert.Equal(t, float64(i), line[logzum.ChunkIndexField])
+		assert.Equal(t, "1", line["id"])
+		chunks = append(chunks, line["message"].(string))
+	}
+	assert.Equal(t, message, strings.Join(chunks, ""))
+}
+
+// receiveLimited receives a line, checking it is within max bytes with its line break.
+func receiveLimited(t *testing.T, lines <-chan []byte, max int) map[string]interface{} {
+	raw := receiveRaw(t, lines)
+	assert.True(t, len(raw)+1 <= max, "line has %d bytes", len(raw)+1)
+	return decode(t, raw)
+}
+
+func TestMaxFieldBytesSink(t *testing.T) {
+	fluentd := newFakeFluentd(t, nil)
+	defer fluentd.Close()
+
+	config := logzum.DefaultConfig
+	config.MaxFieldBytes = 10
+	config.Fluentd = &logzum.FluentdConfig{Address: fluentd.listener.Addr().String(), BatchTimeout: time.Hour}
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "sink", Data: logrus.Fields{"body": strings.Repeat("a", 30)}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	record := receiveForward(t, fluentd.messages).Entries[0].Record
+	assert.Equal(t, "aaaaaaaaaa…[truncated 20 bytes]", record["body"])
+	assert.Equal(t, []interface{}{"body"}, record[logzum.TruncatedFieldsField])
+}
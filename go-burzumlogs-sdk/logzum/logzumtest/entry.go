@@ -0,0 +1,62 @@
+package logzumtest
+
+import "fmt"
+
+var (
+	// TokenField is the field carrying the Burzum token.
+	TokenField = "bztoken"
+	// MessageField is the field carrying the message, as set by logzum.DefaultConfig.
+	MessageField = "message"
+	// LevelField is the field carrying the level, as set by logzum.DefaultConfig.
+	LevelField = "level"
+	// RequestIDField is the field carrying the request ID, as set by bzlogging.RequestIDTorequestIDField.
+	RequestIDField = "requestID"
+)
+
+// Entry is a line received by the server.
+type Entry struct {
+	// Raw is the line, without the line break.
+	Raw []byte
+	// Fields is the decoded line, nil if it is not a JSON object.
+	Fields map[string]interface{}
+	// Protocol is "tcp" or "http".
+	Protocol string
+}
+
+// Field returns the field key as a string, empty if missing.
+func (e Entry) Field(key string) string {
+	v, ok := e.Fields[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// Has reports whether the entry has the field key.
+func (e Entry) Has(key string) bool {
+	_, ok := e.Fields[key]
+	return ok
+}
+
+// Token returns the Burzum token of the entry.
+func (e Entry) Token() string {
+	return e.Field(TokenField)
+}
+
+// Message returns the message of the entry.
+func (e Entry) Message() string {
+	return e.Field(MessageField)
+}
+
+// Level returns the level of the entry.
+func (e Entry) Level() string {
+	return e.Field(LevelField)
+}
+
+// RequestID returns the request ID of the entry.
+func (e Entry) RequestID() string {
+	return e.Field(RequestIDField)
+}
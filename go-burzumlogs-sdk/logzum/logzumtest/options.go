@@ -0,0 +1,42 @@
+package logzumtest
+
+import "time"
+
+type options struct {
+	refuse     bool
+	resetAfter int
+	readDelay  time.Duration
+	dropEvery  int
+}
+
+type Option func(*options)
+
+// WithRefuse makes the server stop listening, so new connections are refused and the open ones closed.
+// HTTP requests are answered with 503 Service Unavailable.
+func WithRefuse(refuse bool) Option {
+	return func(o *options) {
+		o.refuse = refuse
+	}
+}
+
+// WithResetAfter makes the server reset each connection after reading n lines from it, 0 disables it.
+func WithResetAfter(n int) Option {
+	return func(o *options) {
+		o.resetAfter = n
+	}
+}
+
+// WithReadDelay makes the server wait d before each read of a small chunk of a connection, so the
+// senders fill their socket buffers and block, 0 disables it.
+func WithReadDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.readDelay = d
+	}
+}
+
+// WithDropEvery makes the server discard every nth line received, without recording it, 0 disables it.
+func WithDropEvery(n int) Option {
+	return func(o *options) {
+		o.dropEvery = n
+	}
+}
@@ -0,0 +1,323 @@
+// Package logzumtest provides a fake Burzum server recording the entries it receives, with fault injection,
+// to test the logging of an application end to end.
+package logzumtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+// Server is a fake Burzum server accepting lines over TCP, like the logzum hook sends them, and over HTTP,
+// as newline delimited JSON posted to URL.
+type Server struct {
+	mu sync.Mutex
+
+	o options
+
+	addr string
+
+	ln net.Listener
+
+	http *httptest.Server
+
+	conns map[net.Conn]struct{}
+
+	entries []Entry
+
+	// lines counts the lines received, for WithDropEvery
+	lines int
+
+	dropped int
+
+	accepted int
+
+	closed bool
+}
+
+// NewServer starts a server listening on a random local port.
+func NewServer(opts ...Option) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("logzumtest: failed to listen: " + err.Error())
+	}
+	s := &Server{
+		addr:  ln.Addr().String(),
+		conns: make(map[net.Conn]struct{}),
+	}
+	s.http = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.listen(ln)
+	s.Inject(opts...)
+	return s
+}
+
+// Addr returns the TCP address of the server, in the host:port form of logzum.Config.Host.
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// URL returns the HTTP endpoint of the server.
+func (s *Server) URL() string {
+	return s.http.URL
+}
+
+// Config returns logzum.DefaultConfig sending to the server, with a short retry delay.
+func (s *Server) Config() logzum.Config {
+	config := logzum.DefaultConfig
+	config.Host = s.addr
+	config.RetryDelay = 10 * time.Millisecond
+	return config
+}
+
+// Inject changes the faults of the server, the options not given are kept.
+func (s *Server) Inject(opts ...Option) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasRefusing := s.o.refuse
+	for _, o := range opts {
+		o(&s.o)
+	}
+	if s.closed || s.o.refuse == wasRefusing {
+		return
+	}
+
+	if s.o.refuse {
+		s.ln.Close()
+		s.ln = nil
+		for conn := range s.conns {
+			conn.Close()
+		}
+		return
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		panic("logzumtest: failed to listen again: " + err.Error())
+	}
+	s.listen(ln)
+}
+
+// listen serves ln, with the lock held or before the server is shared.
+func (s *Server) listen(ln net.Listener) {
+	s.ln = ln
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.accepted++
+			s.conns[conn] = struct{}{}
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+}
+
+func (s *Server) faults() options {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.o
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(&slowReader{conn: conn, s: s})
+	n := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			s.record(line, "tcp")
+			n++
+			if after := s.faults().resetAfter; after > 0 && n%after == 0 {
+				if tcp, ok := conn.(*net.TCPConn); ok {
+					tcp.SetLinger(0)
+				}
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// slowReader reads small chunks of the connection, waiting the configured delay before each one.
+type slowReader struct {
+	conn net.Conn
+	s    *Server
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if delay := r.s.faults().readDelay; delay > 0 {
+		time.Sleep(delay)
+		if len(p) > 64 {
+			p = p[:64]
+		}
+	}
+	return r.conn.Read(p)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.faults().refuse {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		s.record(line, "http")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) record(line []byte, protocol string) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines++
+	if s.o.dropEvery > 0 && s.lines%s.o.dropEvery == 0 {
+		s.dropped++
+		return
+	}
+
+	e := Entry{Raw: append([]byte(nil), line...), Protocol: protocol}
+	var fields map[string]interface{}
+	if json.Unmarshal(line, &fields) == nil {
+		e.Fields = fields
+	}
+	s.entries = append(s.entries, e)
+}
+
+// Entries returns the entries recorded so far.
+func (s *Server) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...)
+}
+
+// Dropped returns the number of lines discarded by WithDropEvery.
+func (s *Server) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Accepted returns the number of TCP connections accepted.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// Reset forgets the entries and the counters.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+	s.lines = 0
+	s.dropped = 0
+	s.accepted = 0
+}
+
+// Close stops the server and closes the open connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.http.Close()
+}
+
+// WaitForEntries waits up to timeout until n entries are recorded, failing t otherwise.
+func (s *Server) WaitForEntries(t testing.TB, n int, timeout time.Duration) []Entry {
+	deadline := time.Now().Add(timeout)
+	for {
+		entries := s.Entries()
+		if len(entries) >= n {
+			return entries
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("logzumtest: received %d entries, expected %d after %s", len(entries), n, timeout)
+			return entries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// EntriesForRequest returns the entries with the request ID id.
+func (s *Server) EntriesForRequest(id string) []Entry {
+	var entries []Entry
+	for _, e := range s.Entries() {
+		if e.RequestID() == id {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// AssertToken asserts that there are entries and all of them carry token.
+func (s *Server) AssertToken(t testing.TB, token string) bool {
+	entries := s.Entries()
+	if len(entries) == 0 {
+		return assert.Fail(t, "logzumtest: no entries received")
+	}
+	for _, e := range entries {
+		if e.Token() != token {
+			return assert.Fail(t, "logzumtest: entry with an unexpected token", "expected %q, got %q in %s", token, e.Token(), e.Raw)
+		}
+	}
+	return true
+}
+
+// AssertField asserts that at least one entry has the field key equal to value.
+func (s *Server) AssertField(t testing.TB, key string, value interface{}) bool {
+	for _, e := range s.Entries() {
+		if v, ok := e.Fields[key]; ok && assert.ObjectsAreEqualValues(value, v) {
+			return true
+		}
+	}
+	return assert.Fail(t, "logzumtest: no entry with the expected field", "%s=%v", key, value)
+}
+
+// AssertRequestID asserts that at least one entry has the request ID id.
+func (s *Server) AssertRequestID(t testing.TB, id string) bool {
+	if len(s.EntriesForRequest(id)) == 0 {
+		return assert.Fail(t, "logzumtest: no entry for the request", "%s=%s", RequestIDField, id)
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package logzumtest_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum/logzumtest"
+)
+
+func newLogger(t *testing.T, s *logzumtest.Server) (*logrus.Logger, logzum.Hook) {
+	h, err := logzum.NewWithConfig("foo", s.Config())
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.Out = nopWriter{}
+	logger.Level = logrus.DebugLevel
+	logger.Hooks.Add(h)
+	return logger, h
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestServerRecordsEntries(t *testing.T) {
+	s := logzumtest.NewServer()
+	defer s.Close()
+	logger, h := newLogger(t, s)
+	defer h.Close()
+
+	logger.WithFields(logrus.Fields{"requestID": "r1", "status": 200}).Info("done")
+	logger.WithField("requestID", "r2").Warn("slow")
+
+	entries := s.WaitForEntries(t, 2, time.Second)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "done", entries[0].Message())
+	assert.Equal(t, "info", entries[0].Level())
+	assert.Equal(t, "tcp", entries[0].Protocol)
+
+	s.AssertToken(t, "foo")
+	s.AssertField(t, "status", 200)
+	s.AssertRequestID(t, "r2")
+	assert.Len(t, s.EntriesForRequest("r1"), 1)
+
+	mock := &testing.T{}
+	assert.False(t, s.AssertToken(mock, "bar"))
+	assert.False(t, s.AssertField(mock, "status", 500))
+	assert.False(t, s.AssertRequestID(mock, "r3"))
+}
+
+func TestServerHTTP(t *testing.T) {
+	s := logzumtest.NewServer()
+	defer s.Close()
+
+	body := `{"bztoken":"foo","message":"a"}` + "\n" + `{"bztoken":"foo","message":"b"}` + "\n"
+	res, err := http.Post(s.URL(), "application/x-ndjson", strings.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	entries := s.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[1].Message())
+	assert.Equal(t, "http", entries[1].Protocol)
+
+	s.Inject(logzumtest.WithRefuse(true))
+	res, err = http.Post(s.URL(), "application/x-ndjson", strings.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
+func TestServerDropEvery(t *testing.T) {
+	s := logzumtest.NewServer(logzumtest.WithDropEvery(3))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(conn, `{"n":%d}`+"\n", i)
+	}
+	conn.Close()
+
+	entries := s.WaitForEntries(t, 6, time.Second)
+	var received []string
+	for _, e := range entries {
+		received = append(received, e.Field("n"))
+	}
+	assert.Equal(t, []string{"1", "2", "4", "5", "7", "8"}, received)
+	assert.Equal(t, 3, s.Dropped())
+}
+
+func TestServerResetAfter(t *testing.T) {
+	s := logzumtest.NewServer(logzumtest.WithResetAfter(2))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "one\ntwo\n")
+	s.WaitForEntries(t, 2, time.Second)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reset")
+
+	assert.Nil(t, s.Entries()[0].Fields, "plain lines have no fields")
+	assert.Equal(t, "one", string(s.Entries()[0].Raw))
+}
+
+func TestServerRefuse(t *testing.T) {
+	s := logzumtest.NewServer(logzumtest.WithRefuse(true))
+	defer s.Close()
+
+	_, err := net.Dial("tcp", s.Addr())
+	require.Error(t, err)
+
+	config := s.Config()
+	config.MaxRetries = 1
+	h, err := logzum.NewWithConfig("foo", config)
+	require.Error(t, err)
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "lost", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+	require.NoError(t, h.Flush(time.Second))
+	assert.Equal(t, uint64(1), h.Stats().Failed)
+
+	s.Inject(logzumtest.WithRefuse(false))
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+	entries := s.WaitForEntries(t, 1, time.Second)
+	assert.Equal(t, "sent", entries[0].Message())
+}
+
+func TestServerReadDelay(t *testing.T) {
+	s := logzumtest.NewServer(logzumtest.WithReadDelay(20 * time.Millisecond))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	start := time.Now()
+	fmt.Fprint(conn, strings.Repeat("x", 200)+"\n")
+	s.WaitForEntries(t, 1, time.Second)
+	assert.True(t, time.Since(start) >= 60*time.Millisecond, "a 200 bytes line takes 4 slow reads")
+}
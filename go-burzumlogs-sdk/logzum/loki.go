@@ -0,0 +1,398 @@
+package logzum
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The Loki push encodings.
+const (
+	LokiEncodingProtobuf = "protobuf"
+	LokiEncodingJSON     = "json"
+)
+
+// LokiLevelLabel is the label of the entry level, not a field.
+const LokiLevelLabel = "level"
+
+// LokiUnboundedFields are refused as labels, as their values are unique per request or entry and each value makes
+// a new stream in Loki.
+var LokiUnboundedFields = []string{
+	"message", "time", "bztoken", "error", "requestID", "trace.id", "span.id",
+	"http.uri", "http.url", "http.path", "http.target", "http.referer", "http.user_agent", "http.remote_ip",
+	"http.duration", "http.duration_human", "http.bytes_in", "http.bytes_out",
+	"grpc.duration", "grpc.duration_human",
+}
+
+// LokiConfig pushes the entries to the Grafana Loki push API, in place of the transport of the hook. The entries are
+// grouped in streams by the Labels fields, and the formatter writes the other fields in the line.
+type LokiConfig struct {
+	// URL of Loki, http://localhost:3100 by default. /loki/api/v1/push is added to URLs without a path.
+	URL string `yaml:"url"`
+	// TenantID is sent as the X-Scope-OrgID header of multi-tenant Loki.
+	TenantID string `yaml:"tenant-id"`
+	// Username and Password are sent as basic auth when Username is set.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Headers are sent on every push.
+	Headers map[string]string `yaml:"headers"`
+	// TLSConfig is used on https URLs.
+	TLSConfig *tls.Config `yaml:"-"`
+	// Encoding is protobuf, snappy compressed, by default or json.
+	Encoding string `yaml:"encoding"`
+	// Labels are the fields promoted to stream labels, as service, env or level. The label names are the field
+	// names with the characters invalid in Prometheus labels replaced by _. The LokiUnboundedFields are refused.
+	Labels []string `yaml:"labels"`
+	// StaticLabels are added to all the streams.
+	StaticLabels map[string]string `yaml:"static-labels"`
+	// MaxLabelValues is the maximum number of distinct values of each label, 100 by default. Above it the field is
+	// no longer promoted, and stays in the line.
+	MaxLabelValues int `yaml:"max-label-values"`
+	// Timeout of each push attempt, 10s by default.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize is the maximum number of entries per push, 512 by default.
+	BatchSize int `yaml:"batch-size"`
+	// BatchTimeout is the maximum delay of an entry before its batch is pushed, 1s by default.
+	BatchTimeout time.Duration `yaml:"batch-timeout"`
+	// QueueSize is the maximum number of entries waiting for a batch, 2048 by default. Entries above it are dropped.
+	QueueSize int `yaml:"queue-size"`
+	// Retry is the backoff of the pushes answered with 429 or 5xx.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+func (c LokiConfig) withDefaults() LokiConfig {
+	if c.URL == "" {
+		c.URL = "http://localhost:3100"
+	}
+	if c.Encoding == "" {
+		c.Encoding = LokiEncodingProtobuf
+	}
+	if c.MaxLabelValues == 0 {
+		c.MaxLabelValues = 100
+	}
+	return c
+}
+
+func (c LokiConfig) batch() batchConfig {
+	return batchConfig{size: c.BatchSize, timeout: c.BatchTimeout, queueSize: c.QueueSize}
+}
+
+func (c LokiConfig) problems() []string {
+	var problems []string
+	if c.Encoding != "" && c.Encoding != LokiEncodingProtobuf && c.Encoding != LokiEncodingJSON {
+		problems = append(problems, fmt.Sprintf("loki encoding must be %s or %s, got %q", LokiEncodingProtobuf, LokiEncodingJSON, c.Encoding))
+	}
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("loki url %q must be an absolute URL", c.URL))
+		}
+	}
+	for _, field := range c.Labels {
+		for _, unbounded := range LokiUnboundedFields {
+			if field == unbounded {
+				problems = append(problems, fmt.Sprintf("loki label %q is unbounded, keep it in the line", field))
+			}
+		}
+	}
+	if c.MaxLabelValues < 0 {
+		problems = append(problems, fmt.Sprintf("loki max-label-values must not be negative, got %d", c.MaxLabelValues))
+	}
+	return append(problems, batchProblems("loki", c.Timeout, c.batch(), c.Retry)...)
+}
+
+// lokiLabelName replaces the characters invalid in label names by _.
+func lokiLabelName(field string) string {
+	name := []byte(field)
+	for i, c := range name {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// lokiStream is the entries of a label set, in the order they were logged.
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+type lokiEntry struct {
+	time time.Time
+	line string
+}
+
+// lokiSink is the sink pushing batches of streams to Loki.
+type lokiSink struct {
+	*batcher
+	config    LokiConfig
+	url       string
+	formatter logrus.Formatter
+	fields    map[string]interface{}
+	client    *http.Client
+
+	// values and refused are only used by the batcher goroutine
+	values  map[string]map[string]bool
+	refused map[string]bool
+}
+
+// newLokiSink starts a sink formatting the lines with formatter, with fields added to all the entries.
+func newLokiSink(config LokiConfig, formatter logrus.Formatter, fields map[string]interface{}, counters *counters) (*lokiSink, error) {
+	config = config.withDefaults()
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("logzum: invalid Loki url %q: %v", config.URL, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/loki/api/v1/push"
+	}
+
+	s := &lokiSink{
+		config:    config,
+		url:       u.String(),
+		formatter: formatter,
+		fields:    fields,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: config.TLSConfig,
+			},
+		},
+		values:  make(map[string]map[string]bool),
+		refused: make(map[string]bool),
+	}
+	s.batcher = newBatcher("Loki", config.batch(), config.Retry, config.Timeout, counters, s.send)
+	return s, nil
+}
+
+func (s *lokiSink) add(entry *logrus.Entry) {
+	for key, value := range s.fields {
+		entry.Data[key] = value
+	}
+	s.batcher.add(entry)
+}
+
+func (s *lokiSink) close() error {
+	s.batcher.close()
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *lokiSink) endpoint() string {
+	return s.url
+}
+
+// stream returns the labels of entry and its line, formatted without the promoted fields.
+func (s *lokiSink) stream(entry *logrus.Entry) (map[string]string, string, error) {
+	labels := make(map[string]string, len(s.config.Labels)+len(s.config.StaticLabels))
+	for name, value := range s.config.StaticLabels {
+		labels[name] = value
+	}
+
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+	for _, field := range s.config.Labels {
+		var value string
+		if field == LokiLevelLabel {
+			value = entry.Level.String()
+		} else if v, ok := data[field]; ok {
+			value = fmt.Sprint(v)
+		}
+		if value == "" || !s.promote(field, value) {
+			continue
+		}
+		labels[lokiLabelName(field)] = value
+		delete(data, field)
+	}
+	if len(labels) == 0 {
+		// Loki refuses streams without labels
+		labels["source"] = "logzum"
+	}
+
+	line, err := s.formatter.Format(&logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	})
+	return labels, strings.TrimRight(string(line), "\n"), err
+}
+
+// promote reports whether value can be a label value of field, refusing the field above MaxLabelValues.
+func (s *lokiSink) promote(field, value string) bool {
+	if s.refused[field] {
+		return false
+	}
+	values := s.values[field]
+	if values == nil {
+		values = make(map[string]bool)
+		s.values[field] = values
+	}
+	if values[value] {
+		return true
+	}
+	if len(values) >= s.config.MaxLabelValues {
+		s.refused[field] = true
+		log.Printf("BurzumLogs: Loki label %q has more than %d values, keeping it in the line\n", field, s.config.MaxLabelValues)
+		return false
+	}
+	values[value] = true
+	return true
+}
+
+func (s *lokiSink) send(ctx context.Context, entries []*logrus.Entry) (int, error) {
+	streams := make(map[string]*lokiStream)
+	var keys []string
+	rejected := 0
+	for _, entry := range entries {
+		labels, line, err := s.stream(entry)
+		if err != nil {
+			log.Printf("BurzumLogs: error on format %v\n", err)
+			rejected++
+			continue
+		}
+		key := lokiLabels(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{labels: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		t := entry.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		stream.entries = append(stream.entries, lokiEntry{time: t, line: line})
+	}
+	if len(keys) == 0 {
+		return rejected, nil
+	}
+
+	var body []byte
+	var contentType string
+	if s.config.Encoding == LokiEncodingJSON {
+		body, contentType = lokiJSON(keys, streams), "application/json"
+	} else {
+		body, contentType = lokiProtobuf(keys, streams), "application/x-protobuf"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.config.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.config.TenantID)
+	}
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return rejected, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		err := fmt.Errorf("logzum: Loki answered %s", resp.Status)
+		return 0, retryableError{err: err, throttle: retryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return 0, fmt.Errorf("logzum: Loki answered %s: %s", resp.Status, bytes.TrimSpace(content))
+}
+
+// lokiLabels formats labels as a LogQL stream selector, sorted by name.
+func lokiLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func lokiJSON(keys []string, streams map[string]*lokiStream) []byte {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		stream := streams[key]
+		values := make([][2]string, len(stream.entries))
+		for i, entry := range stream.entries {
+			values[i] = [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line}
+		}
+		request.Streams = append(request.Streams, jsonStream{Stream: stream.labels, Values: values})
+	}
+	body, _ := json.Marshal(request)
+	return body
+}
+
+// lokiProtobuf encodes the logproto.PushRequest of Loki, compressed with snappy:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func lokiProtobuf(keys []string, streams map[string]*lokiStream) []byte {
+	var request []byte
+	for _, key := range keys {
+		var stream []byte
+		stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, key)
+		for _, entry := range streams[key].entries {
+			var timestamp []byte
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.time.Unix()))
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.time.Nanosecond()))
+
+			var e []byte
+			e = protowire.AppendTag(e, 1, protowire.BytesType)
+			e = protowire.AppendBytes(e, timestamp)
+			e = protowire.AppendTag(e, 2, protowire.BytesType)
+			e = protowire.AppendString(e, entry.line)
+
+			stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, e)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, stream)
+	}
+	return snappy.Encode(nil, request)
+}
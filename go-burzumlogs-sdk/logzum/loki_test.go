@@ -0,0 +1,331 @@
+package logzum_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+type lokiStream struct {
+	Labels  string
+	Entries []lokiEntry
+}
+
+type lokiEntry struct {
+	Time time.Time
+	Line string
+}
+
+// fakeLoki is a Loki push API, answering the first pushes with statuses and accepting the others.
+type fakeLoki struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	headers  chan http.Header
+	pushes   chan []lokiStream
+}
+
+func newFakeLoki(t *testing.T, statuses ...int) *fakeLoki {
+	l := &fakeLoki{
+		statuses: statuses,
+		headers:  make(chan http.Header, 100),
+		pushes:   make(chan []lokiStream, 100),
+	}
+	l.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		l.headers <- r.Header
+
+		l.mu.Lock()
+		status := http.StatusNoContent
+		if len(l.statuses) > 0 {
+			status, l.statuses = l.statuses[0], l.statuses[1:]
+		}
+		l.mu.Unlock()
+		if status != http.StatusNoContent {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if r.Header.Get("Content-Type") == "application/json" {
+			l.pushes <- decodeLokiJSON(t, body)
+		} else {
+			l.pushes <- decodeLokiProtobuf(t, body)
+		}
+		w.WriteHeader(status)
+	}))
+	return l
+}
+
+func decodeLokiJSON(t *testing.T, body []byte) []lokiStream {
+	var request struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(body, &request))
+
+	var streams []lokiStream
+	for _, s := range request.Streams {
+		names := make([]string, 0, len(s.Stream))
+		for name := range s.Stream {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		labels := "{"
+		for i, name := range names {
+			if i > 0 {
+				labels += ", "
+			}
+			labels += name + "=" + strconv.Quote(s.Stream[name])
+		}
+		stream := lokiStream{Labels: labels + "}"}
+		for _, value := range s.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			require.NoError(t, err)
+			stream.Entries = append(stream.Entries, lokiEntry{Time: time.Unix(0, ns), Line: value[1]})
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// protoFields decodes the fields of a protobuf message, as raw bytes or varints.
+func protoFields(t *testing.T, b []byte) map[protowire.Number][]interface{} {
+	fields := make(map[protowire.Number][]interface{})
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.True(t, n > 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.True(t, n > 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return fields
+}
+
+func decodeLokiProtobuf(t *testing.T, body []byte) []lokiStream {
+	raw, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	var streams []lokiStream
+	for _, s := range protoFields(t, raw)[1] {
+		fields := protoFields(t, s.([]byte))
+		stream := lokiStream{Labels: string(fields[1][0].([]byte))}
+		for _, e := range fields[2] {
+			entry := protoFields(t, e.([]byte))
+			timestamp := protoFields(t, entry[1][0].([]byte))
+			var nanos uint64
+			if len(timestamp[2]) > 0 {
+				nanos = timestamp[2][0].(uint64)
+			}
+			stream.Entries = append(stream.Entries, lokiEntry{
+				Time: time.Unix(int64(timestamp[1][0].(uint64)), int64(nanos)),
+				Line: string(entry[2][0].([]byte)),
+			})
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+func receivePush(t *testing.T, pushes <-chan []lokiStream) []lokiStream {
+	select {
+	case push := <-pushes:
+		return push
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a Loki push")
+		return nil
+	}
+}
+
+func newLokiHook(t *testing.T, loki logzum.LokiConfig) logzum.Hook {
+	if loki.BatchTimeout == 0 {
+		loki.BatchTimeout = time.Hour
+	}
+	loki.Retry.InitialInterval = 10 * time.Millisecond
+
+	config := logzum.DefaultConfig
+	config.Fields = map[string]interface{}{"service": "checkout", "env": "prod"}
+	config.Loki = &loki
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h
+}
+
+func TestLokiPush(t *testing.T) {
+	loki := newFakeLoki(t)
+	defer loki.Close()
+
+	h := newLokiHook(t, logzum.LokiConfig{
+		URL:      loki.URL,
+		TenantID: "team-a",
+		Labels:   []string{"service", "env", "level"},
+	})
+	defer h.Close()
+
+	start := time.Date(2019, 10, 1, 10, 0, 0, 123456789, time.UTC)
+	require.NoError(t, h.Fire(&logrus.Entry{Time: start, Level: logrus.InfoLevel, Message: "first", Data: logrus.Fields{"user": "ana"}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Time: start.Add(time.Second), Level: logrus.ErrorLevel, Message: "failed", Data: logrus.Fields{}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Time: start.Add(2 * time.Second), Level: logrus.InfoLevel, Message: "second", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	streams := receivePush(t, loki.pushes)
+	require.Len(t, streams, 2)
+	assert.Equal(t, `{env="prod", level="info", service="checkout"}`, streams[0].Labels)
+	assert.Equal(t, `{env="prod", level="error", service="checkout"}`, streams[1].Labels)
+
+	require.Len(t, streams[0].Entries, 2)
+	assert.True(t, start.Equal(streams[0].Entries[0].Time), "nanosecond timestamps")
+	line := decode(t, []byte(streams[0].Entries[0].Line))
+	assert.Equal(t, "first", line["message"])
+	assert.Equal(t, "ana", line["user"], "the other fields are kept in the line")
+	assert.NotContains(t, line, "service", "the labels are not repeated in the line")
+	assert.NotContains(t, line, "bztoken")
+	assert.Equal(t, "second", decode(t, []byte(streams[0].Entries[1].Line))["message"])
+
+	headers := <-loki.headers
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "team-a", headers.Get("X-Scope-OrgID"))
+	assert.Equal(t, uint64(3), h.Stats().Sent)
+}
+
+func TestLokiPushJSON(t *testing.T) {
+	loki := newFakeLoki(t)
+	defer loki.Close()
+
+	h := newLokiHook(t, logzum.LokiConfig{
+		URL:          loki.URL,
+		Encoding:     logzum.LokiEncodingJSON,
+		Labels:       []string{"http.method", "missing"},
+		StaticLabels: map[string]string{"cluster": "sa-east-1"},
+		Username:     "user",
+		Password:     "secret",
+	})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "GET", Data: logrus.Fields{"http.method": "GET"}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	streams := receivePush(t, loki.pushes)
+	require.Len(t, streams, 1)
+	assert.Equal(t, `{cluster="sa-east-1", http_method="GET"}`, streams[0].Labels)
+	assert.False(t, streams[0].Entries[0].Time.IsZero())
+
+	headers := <-loki.headers
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Empty(t, headers.Get("X-Scope-OrgID"))
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", headers.Get("Authorization"))
+}
+
+func TestLokiMaxLabelValues(t *testing.T) {
+	loki := newFakeLoki(t)
+	defer loki.Close()
+
+	h := newLokiHook(t, logzum.LokiConfig{URL: loki.URL, Labels: []string{"customer"}, MaxLabelValues: 2})
+	defer h.Close()
+
+	for _, customer := range []string{"a", "b", "a", "c"} {
+		require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Data: logrus.Fields{"customer": customer}}))
+	}
+	require.NoError(t, h.Flush(2*time.Second))
+
+	streams := receivePush(t, loki.pushes)
+	require.Len(t, streams, 3)
+	assert.Equal(t, `{customer="a"}`, streams[0].Labels)
+	assert.Len(t, streams[0].Entries, 2)
+	assert.Equal(t, `{customer="b"}`, streams[1].Labels)
+	assert.Equal(t, `{source="logzum"}`, streams[2].Labels, "the field is refused above MaxLabelValues")
+	assert.Equal(t, "c", decode(t, []byte(streams[2].Entries[0].Line))["customer"])
+}
+
+func TestLokiRetry(t *testing.T) {
+	loki := newFakeLoki(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent, http.StatusBadRequest)
+	defer loki.Close()
+
+	h := newLokiHook(t, logzum.LokiConfig{URL: loki.URL, Labels: []string{"level"}, BatchSize: 1})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "retried", Data: logrus.Fields{}}))
+	assert.Equal(t, "retried", decode(t, []byte(receivePush(t, loki.pushes)[0].Entries[0].Line))["message"])
+	assert.Len(t, loki.headers, 3)
+
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "out of order", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+	stats := h.Stats()
+	assert.Equal(t, uint64(1), stats.Sent)
+	assert.Equal(t, uint64(1), stats.Failed, "400 is not retried")
+	assert.Equal(t, loki.URL+"/loki/api/v1/push", stats.Host)
+}
+
+func TestLokiConfig(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: https://loki.local
+  tenant-id: team-a
+  labels: [service, level]
+  encoding: json
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := logzum.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.LokiConfig{
+		URL:      "https://loki.local",
+		TenantID: "team-a",
+		Labels:   []string{"service", "level"},
+		Encoding: logzum.LokiEncodingJSON,
+	}, config.Loki)
+
+	os.Setenv("BURZUM_LOKI_URL", "http://loki:3100")
+	defer os.Unsetenv("BURZUM_LOKI_URL")
+	os.Setenv("BURZUM_LOKI_LABELS", "service, env")
+	defer os.Unsetenv("BURZUM_LOKI_LABELS")
+	config, err = logzum.ConfigFromEnv("BURZUM")
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.LokiConfig{URL: "http://loki:3100", Labels: []string{"service", "env"}}, config.Loki)
+
+	err = logzum.Config{
+		Loki:       &logzum.LokiConfig{URL: "loki", Encoding: "xml", Labels: []string{"service", "requestID"}},
+		OTLP:       &logzum.OTLPConfig{},
+		Formatter:  &logrus.JSONFormatter{},
+		MaxRetries: 1,
+	}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `loki label "requestID" is unbounded`)
+		assert.Contains(t, err.Error(), `loki encoding must be protobuf or json, got "xml"`)
+		assert.Contains(t, err.Error(), `loki url "loki" must be an absolute URL`)
+		assert.Contains(t, err.Error(), "otlp and loki can't be used together")
+		assert.NotContains(t, err.Error(), "host")
+	}
+}
@@ -0,0 +1,300 @@
+package logzum
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+)
+
+// The OTLP protocols.
+const (
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http/protobuf"
+)
+
+const (
+	// otlpScopeName is the instrumentation scope of the exported records.
+	otlpScopeName = "github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+
+	// the fields set by the tracing middlewares, used when the entry has no span in its context
+	otlpTraceIDField = "trace.id"
+	otlpSpanIDField  = "span.id"
+)
+
+// OTLPConfig exports the entries as OTLP log records to an OpenTelemetry collector, in place of the formatter and
+// the transport of the hook.
+type OTLPConfig struct {
+	// Protocol is grpc, the default, or http/protobuf.
+	Protocol string `yaml:"protocol"`
+	// Endpoint is the host:port of the gRPC collector, localhost:4317 by default, or the URL of the HTTP one,
+	// http://localhost:4318/v1/logs by default. /v1/logs is added to URLs without a path.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure disables TLS on gRPC, and on HTTP endpoints given without a scheme.
+	Insecure bool `yaml:"insecure"`
+	// TLSConfig is used on secure connections.
+	TLSConfig *tls.Config `yaml:"-"`
+	// Headers are sent on every export, as gRPC metadata or HTTP headers.
+	Headers map[string]string `yaml:"headers"`
+	// Compression is gzip or none, the default.
+	Compression string `yaml:"compression"`
+	// Timeout of each export attempt, 10s by default.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize is the maximum number of records per export, 512 by default.
+	BatchSize int `yaml:"batch-size"`
+	// BatchTimeout is the maximum delay of a record before its batch is exported, 1s by default.
+	BatchTimeout time.Duration `yaml:"batch-timeout"`
+	// QueueSize is the maximum number of records waiting for a batch, 2048 by default. Records above it are dropped.
+	QueueSize int `yaml:"queue-size"`
+	// Retry is the backoff of the exports failed with the retryable errors of the OTLP specification: the gRPC
+	// codes Canceled, DeadlineExceeded, Aborted, OutOfRange, Unavailable, DataLoss and ResourceExhausted with a
+	// retry delay, and the HTTP statuses 429, 502, 503 and 504.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+func (c OTLPConfig) withDefaults() OTLPConfig {
+	if c.Protocol == "" {
+		c.Protocol = OTLPProtocolGRPC
+	}
+	if c.Endpoint == "" {
+		if c.Protocol == OTLPProtocolHTTP {
+			c.Endpoint = "http://localhost:4318"
+		} else {
+			c.Endpoint = "localhost:4317"
+		}
+	}
+	return c
+}
+
+func (c OTLPConfig) batch() batchConfig {
+	return batchConfig{size: c.BatchSize, timeout: c.BatchTimeout, queueSize: c.QueueSize}
+}
+
+func (c OTLPConfig) problems() []string {
+	var problems []string
+	if c.Protocol != "" && c.Protocol != OTLPProtocolGRPC && c.Protocol != OTLPProtocolHTTP {
+		problems = append(problems, fmt.Sprintf("otlp protocol must be %s or %s, got %q", OTLPProtocolGRPC, OTLPProtocolHTTP, c.Protocol))
+	}
+	if c.Compression != "" && c.Compression != "gzip" && c.Compression != "none" {
+		problems = append(problems, fmt.Sprintf("otlp compression must be gzip or none, got %q", c.Compression))
+	}
+	return append(problems, batchProblems("otlp", c.Timeout, c.batch(), c.Retry)...)
+}
+
+// otlpSeverities maps the logrus levels to the first severity number of their range.
+var otlpSeverities = map[logrus.Level]logspb.SeverityNumber{
+	logrus.PanicLevel: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4,
+	logrus.FatalLevel: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	logrus.ErrorLevel: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	logrus.WarnLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	logrus.InfoLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	logrus.DebugLevel: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	logrus.TraceLevel: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+}
+
+// otlpRecord converts entry to a log record. The trace and span ids are taken from the span of the entry context,
+// or its trace context, or else from the trace.id and span.id fields.
+func otlpRecord(entry *logrus.Entry, now time.Time) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       otlpSeverities[entry.Level],
+		SeverityText:         entry.Level.String(),
+		Body:                 otlpValue(entry.Message),
+	}
+	if !entry.Time.IsZero() {
+		record.TimeUnixNano = uint64(entry.Time.UnixNano())
+	}
+
+	skip := map[string]bool{}
+	if entry.Context != nil {
+		if sc := trace.SpanContextFromContext(entry.Context); sc.IsValid() {
+			traceID, spanID := sc.TraceID(), sc.SpanID()
+			record.TraceId, record.SpanId = traceID[:], spanID[:]
+			record.Flags = uint32(sc.TraceFlags())
+		} else if tc, ok := tracecontext.Extract(entry.Context); ok {
+			record.TraceId, _ = hex.DecodeString(tc.TraceID)
+			record.SpanId, _ = hex.DecodeString(tc.SpanID)
+			if tc.Sampled {
+				record.Flags = uint32(trace.FlagsSampled)
+			}
+		}
+	}
+	if record.TraceId == nil {
+		traceID, traceOK := otlpID(entry.Data[otlpTraceIDField], 16)
+		spanID, spanOK := otlpID(entry.Data[otlpSpanIDField], 8)
+		if traceOK && spanOK {
+			record.TraceId, record.SpanId = traceID, spanID
+			skip[otlpTraceIDField], skip[otlpSpanIDField] = true, true
+		}
+	}
+
+	record.Attributes = otlpAttributes(entry.Data, skip)
+	return record
+}
+
+// otlpID decodes a hex id of size bytes, left padding the shorter ones as the 64-bit Jaeger trace ids.
+func otlpID(value interface{}, size int) ([]byte, bool) {
+	s, ok := value.(string)
+	if !ok || s == "" || len(s) > size*2 {
+		return nil, false
+	}
+	id, err := hex.DecodeString(strings.Repeat("0", size*2-len(s)) + s)
+	if err != nil {
+		return nil, false
+	}
+	return id, true
+}
+
+func otlpAttributes(fields map[string]interface{}, skip map[string]bool) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	attributes := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, key := range keys {
+		attributes = append(attributes, &commonpb.KeyValue{Key: key, Value: otlpValue(fields[key])})
+	}
+	return attributes
+}
+
+// otlpValue converts a field to the typed value of OTLP, slices and maps included.
+func otlpValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return otlpValue(v.Format(time.RFC3339Nano))
+	case error:
+		return otlpValue(v.Error())
+	case fmt.Stringer:
+		return otlpValue(v.String())
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: rv.Int()}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(rv.Uint())}}
+	case reflect.Float32, reflect.Float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: rv.Float()}}
+	case reflect.Slice, reflect.Array:
+		values := make([]*commonpb.AnyValue, rv.Len())
+		for i := range values {
+			values[i] = otlpValue(rv.Index(i).Interface())
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case reflect.Map:
+		fields := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			fields[fmt.Sprint(key.Interface())] = rv.MapIndex(key).Interface()
+		}
+		kvlist := &commonpb.KeyValueList{Values: otlpAttributes(fields, nil)}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kvlist}}
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return &commonpb.AnyValue{}
+		}
+		return otlpValue(rv.Elem().Interface())
+	}
+	return otlpValue(fmt.Sprint(value))
+}
+
+// otlpClient sends an export request with one of the OTLP protocols.
+type otlpClient interface {
+	export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error)
+	close() error
+}
+
+// otlpExporter is the sink exporting batches of records to a collector.
+type otlpExporter struct {
+	*batcher
+	config   OTLPConfig
+	resource *resourcepb.Resource
+	client   otlpClient
+}
+
+// newOTLPExporter starts an exporter, with the fields as resource attributes.
+func newOTLPExporter(config OTLPConfig, fields map[string]interface{}, counters *counters) (*otlpExporter, error) {
+	config = config.withDefaults()
+
+	var client otlpClient
+	var err error
+	if config.Protocol == OTLPProtocolHTTP {
+		client, err = newOTLPHTTPClient(config)
+	} else {
+		client, err = newOTLPGRPCClient(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e := &otlpExporter{
+		config:   config,
+		resource: &resourcepb.Resource{Attributes: otlpAttributes(fields, nil)},
+		client:   client,
+	}
+	e.batcher = newBatcher("OTLP", config.batch(), config.Retry, config.Timeout, counters, e.send)
+	return e, nil
+}
+
+func (e *otlpExporter) send(ctx context.Context, entries []*logrus.Entry) (int, error) {
+	now := time.Now()
+	records := make([]*logspb.LogRecord, len(entries))
+	for i, entry := range entries {
+		records[i] = otlpRecord(entry, now)
+	}
+
+	request := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: e.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpScopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+	response, err := e.client.export(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+
+	partial := response.GetPartialSuccess()
+	if partial.GetRejectedLogRecords() > 0 || partial.GetErrorMessage() != "" {
+		log.Printf("BurzumLogs: collector rejected %d log records: %s\n", partial.GetRejectedLogRecords(), partial.GetErrorMessage())
+	}
+	return int(partial.GetRejectedLogRecords()), nil
+}
+
+func (e *otlpExporter) close() error {
+	e.batcher.close()
+	return e.client.close()
+}
+
+func (e *otlpExporter) endpoint() string {
+	return e.config.Endpoint
+}
@@ -0,0 +1,203 @@
+package logzum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpGRPCClient exports to the LogsService of a gRPC collector.
+type otlpGRPCClient struct {
+	conn        *grpc.ClientConn
+	client      collogspb.LogsServiceClient
+	metadata    metadata.MD
+	callOptions []grpc.CallOption
+}
+
+func newOTLPGRPCClient(config OTLPConfig) (*otlpGRPCClient, error) {
+	creds := credentials.NewTLS(config.TLSConfig)
+	if config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("logzum: unable to dial the OTLP collector: %v", err)
+	}
+
+	c := &otlpGRPCClient{
+		conn:     conn,
+		client:   collogspb.NewLogsServiceClient(conn),
+		metadata: metadata.New(config.Headers),
+	}
+	if config.Compression == "gzip" {
+		c.callOptions = append(c.callOptions, grpc.UseCompressor(grpcgzip.Name))
+	}
+	return c, nil
+}
+
+func (c *otlpGRPCClient) export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	ctx = metadata.NewOutgoingContext(ctx, c.metadata)
+	response, err := c.client.Export(ctx, request, c.callOptions...)
+	if err != nil {
+		return nil, grpcExportError(err)
+	}
+	return response, nil
+}
+
+func (c *otlpGRPCClient) close() error {
+	return c.conn.Close()
+}
+
+// grpcExportError wraps the retryable errors, ResourceExhausted only when the collector sent a retry delay.
+func grpcExportError(err error) error {
+	s := status.Convert(err)
+
+	var throttle time.Duration
+	throttled := false
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			throttle, throttled = info.RetryDelay.AsDuration(), true
+		}
+	}
+
+	switch s.Code() {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return retryableError{err: err, throttle: throttle}
+	case codes.ResourceExhausted:
+		if throttled {
+			return retryableError{err: err, throttle: throttle}
+		}
+	}
+	return err
+}
+
+// otlpHTTPClient posts binary protobuf requests to an HTTP collector.
+type otlpHTTPClient struct {
+	url     string
+	headers map[string]string
+	gzip    bool
+	client  *http.Client
+}
+
+func newOTLPHTTPClient(config OTLPConfig) (*otlpHTTPClient, error) {
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		if config.Insecure {
+			endpoint = "http://" + endpoint
+		} else {
+			endpoint = "https://" + endpoint
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("logzum: invalid OTLP endpoint %q: %v", config.Endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+
+	return &otlpHTTPClient{
+		url:     u.String(),
+		headers: config.Headers,
+		gzip:    config.Compression == "gzip",
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: config.TLSConfig,
+			},
+		},
+	}, nil
+}
+
+func (c *otlpHTTPClient) export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if c.gzip {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body = compressed.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the collector may be restarting
+		return nil, retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, retryableError{err: err}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		response := &collogspb.ExportLogsServiceResponse{}
+		if err := proto.Unmarshal(content, response); err != nil {
+			return nil, fmt.Errorf("logzum: invalid OTLP response: %v", err)
+		}
+		return response, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		err := fmt.Errorf("logzum: OTLP collector answered %s", resp.Status)
+		return nil, retryableError{err: err, throttle: retryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return nil, fmt.Errorf("logzum: OTLP collector answered %s: %s", resp.Status, bytes.TrimSpace(content))
+}
+
+func (c *otlpHTTPClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// retryAfter parses the seconds or the HTTP date of a Retry-After header.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
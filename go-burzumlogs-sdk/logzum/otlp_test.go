@@ -0,0 +1,469 @@
+package logzum_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/tracecontext"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+// fakeCollector is an in-process OTLP gRPC collector, answering the first requests with errs, nil accepting them.
+type fakeCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	errs     []error
+	rejected int64
+
+	requests chan *collogspb.ExportLogsServiceRequest
+	metadata chan metadata.MD
+}
+
+func newFakeCollector(t *testing.T, errs ...error) (*fakeCollector, string, func()) {
+	c := &fakeCollector{
+		errs:     errs,
+		requests: make(chan *collogspb.ExportLogsServiceRequest, 100),
+		metadata: make(chan metadata.MD, 100),
+	}
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, c)
+	go server.Serve(l)
+	return c, l.Addr().String(), server.Stop
+}
+
+func (c *fakeCollector) Export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.metadata <- md
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.requests <- request
+
+	response := &collogspb.ExportLogsServiceResponse{}
+	if c.rejected > 0 {
+		response.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: c.rejected, ErrorMessage: "too old"}
+	}
+	return response, nil
+}
+
+func receiveRequest(t *testing.T, requests <-chan *collogspb.ExportLogsServiceRequest) *collogspb.ExportLogsServiceRequest {
+	select {
+	case request := <-requests:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an export request")
+		return nil
+	}
+}
+
+func records(request *collogspb.ExportLogsServiceRequest) []*logspb.LogRecord {
+	return request.ResourceLogs[0].ScopeLogs[0].LogRecords
+}
+
+// attributes converts the OTLP attributes back to plain values.
+func attributes(kvs []*commonpb.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = plainValue(kv.Value)
+	}
+	return m
+}
+
+func plainValue(v *commonpb.AnyValue) interface{} {
+	switch value := v.Value.(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return value.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return value.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, len(value.ArrayValue.Values))
+		for i, v := range value.ArrayValue.Values {
+			values[i] = plainValue(v)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		return attributes(value.KvlistValue.Values)
+	}
+	return nil
+}
+
+func newOTLPHook(t *testing.T, config logzum.OTLPConfig) logzum.Hook {
+	if config.BatchTimeout == 0 {
+		config.BatchTimeout = 10 * time.Millisecond
+	}
+	config.Retry.InitialInterval = 10 * time.Millisecond
+	config.Retry.MaxElapsedTime = 2 * time.Second
+
+	hookConfig := logzum.DefaultConfig
+	hookConfig.MinLevel = logrus.TraceLevel
+	hookConfig.Fields = map[string]interface{}{"service.name": "checkout"}
+	hookConfig.OTLP = &config
+	h, err := logzum.NewWithConfig("foo", hookConfig)
+	require.NoError(t, err)
+	return h
+}
+
+func TestOTLPRecord(t *testing.T) {
+	collector, endpoint, stop := newFakeCollector(t)
+	defer stop()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{
+		Endpoint: endpoint,
+		Insecure: true,
+		Headers:  map[string]string{"authorization": "Bearer secret"},
+	})
+	defer h.Close()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	now := time.Date(2019, 10, 1, 10, 0, 0, 123456789, time.UTC)
+	require.NoError(t, h.Fire(&logrus.Entry{
+		Time:    now,
+		Level:   logrus.WarnLevel,
+		Message: "payment declined",
+		Context: trace.ContextWithSpanContext(context.Background(), sc),
+		Data: logrus.Fields{
+			"http.status": 502,
+			"retry":       true,
+			"ratio":       0.5,
+			"error":       errors.New("boom"),
+			"tags":        []string{"a", "b"},
+			"labels":      map[string]string{"team": "payments"},
+		},
+	}))
+
+	request := receiveRequest(t, collector.requests)
+	assert.Equal(t, []string{"Bearer secret"}, (<-collector.metadata).Get("authorization"))
+
+	resource := request.ResourceLogs[0].Resource
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout"}, attributes(resource.Attributes), "the token is sent to Burzum only")
+	assert.Equal(t, "github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum", request.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+
+	require.Len(t, records(request), 1)
+	record := records(request)[0]
+	assert.Equal(t, uint64(now.UnixNano()), record.TimeUnixNano)
+	assert.NotZero(t, record.ObservedTimeUnixNano)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, record.SeverityNumber)
+	assert.Equal(t, "warning", record.SeverityText)
+	assert.Equal(t, "payment declined", record.Body.GetStringValue())
+	assert.Equal(t, map[string]interface{}{
+		"http.status": int64(502),
+		"retry":       true,
+		"ratio":       0.5,
+		"error":       "boom",
+		"tags":        []interface{}{"a", "b"},
+		"labels":      map[string]interface{}{"team": "payments"},
+	}, attributes(record.Attributes))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", toTraceID(record.TraceId).String())
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(record.SpanId))
+	assert.Equal(t, uint32(trace.FlagsSampled), record.Flags)
+}
+
+func toTraceID(b []byte) (id trace.TraceID) {
+	copy(id[:], b)
+	return id
+}
+
+func TestOTLPSeverities(t *testing.T) {
+	collector, endpoint, stop := newFakeCollector(t)
+	defer stop()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: endpoint, Insecure: true, BatchSize: 7, BatchTimeout: time.Hour})
+	defer h.Close()
+
+	for _, level := range logrus.AllLevels {
+		require.NoError(t, h.Fire(&logrus.Entry{Level: level, Data: logrus.Fields{}}))
+	}
+
+	var severities []logspb.SeverityNumber
+	for _, record := range records(receiveRequest(t, collector.requests)) {
+		severities = append(severities, record.SeverityNumber)
+	}
+	assert.Equal(t, []logspb.SeverityNumber{24, 21, 17, 13, 9, 5, 1}, severities)
+}
+
+func TestOTLPTraceIDs(t *testing.T) {
+	collector, endpoint, stop := newFakeCollector(t)
+	defer stop()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: endpoint, Insecure: true, BatchSize: 3, BatchTimeout: time.Hour})
+	defer h.Close()
+
+	tc := tracecontext.TraceContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331", Sampled: true}
+	require.NoError(t, h.Fire(&logrus.Entry{Context: tracecontext.Inject(context.Background(), tc), Data: logrus.Fields{}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Data: logrus.Fields{"trace.id": "a3ce929d0e0e4736", "span.id": "00f067aa0ba902b7"}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Data: logrus.Fields{"trace.id": "not hex", "span.id": "00f067aa0ba902b7"}}))
+
+	rs := records(receiveRequest(t, collector.requests))
+	require.Len(t, rs, 3)
+
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", toTraceID(rs[0].TraceId).String())
+	assert.Equal(t, uint32(trace.FlagsSampled), rs[0].Flags)
+
+	assert.Equal(t, "0000000000000000a3ce929d0e0e4736", toTraceID(rs[1].TraceId).String(), "64-bit ids are left padded")
+	assert.Empty(t, rs[1].Attributes, "the ids are not repeated as attributes")
+
+	assert.Nil(t, rs[2].TraceId)
+	assert.Equal(t, map[string]interface{}{"trace.id": "not hex", "span.id": "00f067aa0ba902b7"}, attributes(rs[2].Attributes))
+}
+
+func TestOTLPBatching(t *testing.T) {
+	collector, endpoint, stop := newFakeCollector(t)
+	defer stop()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: endpoint, Insecure: true, BatchSize: 2, BatchTimeout: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Data: logrus.Fields{"i": i}}))
+	}
+	assert.Len(t, records(receiveRequest(t, collector.requests)), 2, "full batches are exported at once")
+
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Len(t, records(receiveRequest(t, collector.requests)), 1, "Flush exports the partial batch")
+
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Data: logrus.Fields{}}))
+	require.NoError(t, h.Close())
+	assert.Len(t, records(receiveRequest(t, collector.requests)), 1, "Close exports the queued records")
+
+	stats := h.Stats()
+	assert.Equal(t, endpoint, stats.Host)
+	assert.Equal(t, uint64(4), stats.Sent)
+	assert.Equal(t, uint64(0), stats.Queued)
+}
+
+func TestOTLPRetry(t *testing.T) {
+	throttled, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(20 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	collector, endpoint, stop := newFakeCollector(t,
+		status.Error(codes.Unavailable, "restarting"),
+		throttled.Err(),
+		nil,
+		// ResourceExhausted without a retry delay and the other codes are permanent
+		status.Error(codes.ResourceExhausted, "quota"),
+		status.Error(codes.InvalidArgument, "bad request"),
+	)
+	defer stop()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: endpoint, Insecure: true, BatchSize: 1})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "retried", Data: logrus.Fields{}}))
+	assert.Equal(t, "retried", records(receiveRequest(t, collector.requests))[0].Body.GetStringValue())
+	assert.Len(t, collector.metadata, 3, "two failed attempts and the accepted one")
+
+	for _, message := range []string{"exhausted", "invalid"} {
+		require.NoError(t, h.Fire(&logrus.Entry{Message: message, Data: logrus.Fields{}}))
+	}
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Equal(t, logzum.Stats{Host: endpoint, Sent: 1, Failed: 2}, h.Stats())
+	assert.Empty(t, collector.requests)
+}
+
+func TestOTLPRetryGivesUp(t *testing.T) {
+	_, endpoint, stop := newFakeCollector(t,
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.Unavailable, "down"),
+	)
+	defer stop()
+
+	config := logzum.DefaultConfig
+	config.OTLP = &logzum.OTLPConfig{
+		Endpoint: endpoint,
+		Insecure: true,
+		Retry:    logzum.RetryConfig{InitialInterval: 10 * time.Millisecond, MaxElapsedTime: 15 * time.Millisecond},
+	}
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Equal(t, uint64(1), h.Stats().Failed, "given up after MaxElapsedTime")
+}
+
+func TestOTLPPartialSuccess(t *testing.T) {
+	collector, endpoint, stop := newFakeCollector(t)
+	defer stop()
+	collector.rejected = 1
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: endpoint, Insecure: true, BatchSize: 3})
+	defer h.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, h.Fire(&logrus.Entry{Data: logrus.Fields{}}))
+	}
+	receiveRequest(t, collector.requests)
+	require.NoError(t, h.Flush(2*time.Second))
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, uint64(1), stats.Failed, "the rejected records are failed")
+}
+
+func TestOTLPReconfigure(t *testing.T) {
+	first, firstEndpoint, stopFirst := newFakeCollector(t)
+	defer stopFirst()
+	second, secondEndpoint, stopSecond := newFakeCollector(t)
+	defer stopSecond()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{Endpoint: firstEndpoint, Insecure: true, BatchTimeout: time.Hour})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "first", Data: logrus.Fields{}}))
+
+	config := logzum.DefaultConfig
+	config.OTLP = &logzum.OTLPConfig{Endpoint: secondEndpoint, Insecure: true, BatchTimeout: time.Hour}
+	require.NoError(t, h.Reconfigure(config))
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "second", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+
+	assert.Equal(t, "first", records(receiveRequest(t, first.requests))[0].Body.GetStringValue(),
+		"the replaced exporter exports its records")
+	assert.Equal(t, "second", records(receiveRequest(t, second.requests))[0].Body.GetStringValue())
+	assert.Equal(t, secondEndpoint, h.Stats().Host)
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	requests := make(chan *collogspb.ExportLogsServiceRequest, 10)
+	var mu sync.Mutex
+	answers := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "tenant-a", r.Header.Get("X-Tenant"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		mu.Lock()
+		answer := answers[0]
+		answers = answers[1:]
+		mu.Unlock()
+		if answer != http.StatusOK {
+			http.Error(w, http.StatusText(answer), answer)
+			return
+		}
+
+		body, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		raw, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		request := &collogspb.ExportLogsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(raw, request))
+		requests <- request
+
+		response, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	h := newOTLPHook(t, logzum.OTLPConfig{
+		Protocol:    logzum.OTLPProtocolHTTP,
+		Endpoint:    server.URL,
+		Headers:     map[string]string{"X-Tenant": "tenant-a"},
+		Compression: "gzip",
+		BatchSize:   1,
+	})
+	defer h.Close()
+
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: "over http", Data: logrus.Fields{"attempt": 1}}))
+	record := records(receiveRequest(t, requests))[0]
+	assert.Equal(t, "over http", record.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, record.SeverityNumber)
+	assert.Equal(t, map[string]interface{}{"attempt": int64(1)}, attributes(record.Attributes))
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "refused", Data: logrus.Fields{}}))
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Equal(t, uint64(1), h.Stats().Failed, "400 is not retried")
+}
+
+func TestOTLPConfig(t *testing.T) {
+	path := writeConfig(t, `
+token: secret
+otlp:
+  protocol: http/protobuf
+  endpoint: https://collector.local:4318
+  headers:
+    x-tenant: payments
+  batch-size: 100
+  retry:
+    max-elapsed-time: 30s
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := logzum.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.OTLPConfig{
+		Protocol:  logzum.OTLPProtocolHTTP,
+		Endpoint:  "https://collector.local:4318",
+		Headers:   map[string]string{"x-tenant": "payments"},
+		BatchSize: 100,
+		Retry:     logzum.RetryConfig{MaxElapsedTime: 30 * time.Second},
+	}, config.OTLP)
+
+	os.Setenv("BURZUM_OTLP_ENDPOINT", "collector.local:4317")
+	defer os.Unsetenv("BURZUM_OTLP_ENDPOINT")
+	os.Setenv("BURZUM_OTLP_INSECURE", "true")
+	defer os.Unsetenv("BURZUM_OTLP_INSECURE")
+	config, err = logzum.ConfigFromEnv("BURZUM")
+	require.NoError(t, err)
+	assert.Equal(t, &logzum.OTLPConfig{Endpoint: "collector.local:4317", Insecure: true}, config.OTLP)
+
+	err = logzum.Config{OTLP: &logzum.OTLPConfig{}, MaxRetries: 1}.Validate()
+	assert.NoError(t, err, "host and formatter are not required by the exporter")
+
+	err = logzum.Config{OTLP: &logzum.OTLPConfig{Protocol: "http/json", Compression: "zstd", BatchSize: -1}, MaxRetries: 1}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `otlp protocol must be grpc or http/protobuf, got "http/json"`)
+		assert.Contains(t, err.Error(), `otlp compression must be gzip or none, got "zstd"`)
+		assert.Contains(t, err.Error(), "otlp values must not be negative")
+	}
+}
@@ -0,0 +1,311 @@
+package logzum
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//RedactAction is what is done to a sensitive value
+type RedactAction int
+
+const (
+	// RedactMask replaces the value by the mask.
+	RedactMask RedactAction = iota
+	// RedactDrop removes the field holding the value.
+	RedactDrop
+	// RedactHash replaces the value by its keyed HMAC, keeping equal values correlatable.
+	RedactHash
+)
+
+var redactActionNames = []string{"mask", "drop", "hash"}
+
+func (a RedactAction) String() string {
+	if a < 0 || int(a) >= len(redactActionNames) {
+		return fmt.Sprintf("RedactAction(%d)", int(a))
+	}
+	return redactActionNames[a]
+}
+
+// UnmarshalText parses mask, drop or hash.
+func (a *RedactAction) UnmarshalText(text []byte) error {
+	for i, name := range redactActionNames {
+		if strings.EqualFold(name, string(text)) {
+			*a = RedactAction(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("logzum: unknown redact action %q", text)
+}
+
+//KeyRule redacts the fields with the given name, at any depth and ignoring case
+type KeyRule struct {
+	Key    string       `yaml:"key"`
+	Action RedactAction `yaml:"action"`
+}
+
+//ValueRule redacts the parts of string values matching Pattern and accepted by Valid
+type ValueRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Valid filters out false positives of the pattern, e.g. with a check digit, all matches are redacted if nil.
+	Valid  func(match string) bool
+	Action RedactAction
+}
+
+var (
+	// DefaultRedactedValue replaces the masked values.
+	DefaultRedactedValue = "[REDACTED]"
+
+	// CardNumberRule masks credit card numbers passing the Luhn check.
+	CardNumberRule = ValueRule{
+		Name:    "card",
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Valid:   luhn,
+	}
+	// EmailRule masks email addresses.
+	EmailRule = ValueRule{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+	// CPFRule masks valid CPFs, formatted or not.
+	CPFRule = ValueRule{
+		Name:    "cpf",
+		Pattern: regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`),
+		Valid:   validCPF,
+	}
+	// CNPJRule masks valid CNPJs, formatted or not.
+	CNPJRule = ValueRule{
+		Name:    "cnpj",
+		Pattern: regexp.MustCompile(`\b\d{2}\.?\d{3}\.?\d{3}/?\d{4}-?\d{2}\b`),
+		Valid:   validCNPJ,
+	}
+
+	// DefaultKeyRules drops passwords and masks tokens and CPFs.
+	DefaultKeyRules = []KeyRule{
+		{Key: "password", Action: RedactDrop},
+		{Key: "token", Action: RedactMask},
+		{Key: "cpf", Action: RedactMask},
+	}
+	// DefaultValueRules masks card numbers, emails, CPFs and CNPJs.
+	DefaultValueRules = []ValueRule{CNPJRule, CPFRule, CardNumberRule, EmailRule}
+)
+
+//RedactionConfig holds the redaction configs, applied to the message and the fields before formatting
+type RedactionConfig struct {
+	Keys []KeyRule `yaml:"keys"`
+	// Values are given by the names of DefaultValueRules in YAML files.
+	Values []ValueRule `yaml:"-"`
+	// HashKey is the HMAC key of RedactHash, values are masked if it is empty.
+	HashKey []byte `yaml:"-"`
+	// Mask replaces the masked values, DefaultRedactedValue if empty.
+	Mask string `yaml:"mask"`
+}
+
+// DefaultRedactionConfig returns a config with the default key and value rules.
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		Keys:   append([]KeyRule(nil), DefaultKeyRules...),
+		Values: append([]ValueRule(nil), DefaultValueRules...),
+	}
+}
+
+type redactor struct {
+	config RedactionConfig
+
+	keys map[string]RedactAction
+}
+
+func newRedactor(config RedactionConfig) *redactor {
+	if config.Mask == "" {
+		config.Mask = DefaultRedactedValue
+	}
+	keys := make(map[string]RedactAction, len(config.Keys))
+	for _, rule := range config.Keys {
+		keys[strings.ToLower(rule.Key)] = rule.Action
+	}
+	return &redactor{config: config, keys: keys}
+}
+
+// redact replaces the sensitive values of entry, which must not be shared.
+func (r *redactor) redact(entry *logrus.Entry) {
+	entry.Message, _ = r.string(entry.Message)
+	for key, value := range entry.Data {
+		if redacted, ok := r.field(key, value); ok {
+			entry.Data[key] = redacted
+		} else {
+			delete(entry.Data, key)
+		}
+	}
+}
+
+// field returns the redacted value of a field, or false if it must be dropped.
+func (r *redactor) field(key string, value interface{}) (interface{}, bool) {
+	if action, ok := r.keys[strings.ToLower(key)]; ok {
+		if action == RedactDrop {
+			return nil, false
+		}
+		s, ok := value.(string)
+		if !ok {
+			b, _ := json.Marshal(value)
+			s = string(b)
+		}
+		return r.apply(action, s), true
+	}
+	return r.value(value)
+}
+
+func (r *redactor) value(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64,
+		time.Time, time.Duration:
+		return v, true
+	case string:
+		return r.string(v)
+	case error:
+		return r.string(v.Error())
+	case fmt.Stringer:
+		// text formatters print the String of the value
+		return r.string(v.String())
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if redacted, ok := r.field(key, value); ok {
+				m[key] = redacted
+			}
+		}
+		return m, true
+	case []interface{}:
+		s := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			if redacted, ok := r.value(value); ok {
+				s = append(s, redacted)
+			}
+		}
+		return s, true
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		// named string types, as type CPF string
+		return r.string(rv.String())
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
+		// walk the value as the formatter will encode it, honouring the json tags and marshalers
+		b, err := json.Marshal(value)
+		if err != nil {
+			return value, true
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return value, true
+		}
+		return r.value(generic)
+	}
+	return value, true
+}
+
+// string applies the value rules to s, reporting false if the value must be dropped.
+func (r *redactor) string(s string) (string, bool) {
+	for _, rule := range r.config.Values {
+		drop := false
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.Valid != nil && !rule.Valid(match) {
+				return match
+			}
+			if rule.Action == RedactDrop {
+				drop = true
+			}
+			return r.apply(rule.Action, match)
+		})
+		if drop {
+			return r.config.Mask, false
+		}
+	}
+	return s, true
+}
+
+func (r *redactor) apply(action RedactAction, s string) string {
+	if action == RedactHash && len(r.config.HashKey) > 0 {
+		mac := hmac.New(sha256.New, r.config.HashKey)
+		mac.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return r.config.Mask
+}
+
+func digits(s string) []int {
+	d := make([]int, 0, len(s))
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			d = append(d, int(c-'0'))
+		}
+	}
+	return d
+}
+
+func luhn(s string) bool {
+	d := digits(s)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range d {
+		n := d[len(d)-1-i]
+		if i%2 == 1 {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+func allEqual(d []int) bool {
+	for _, n := range d {
+		if n != d[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDigit computes a modulo 11 check digit of d with the given weights.
+func checkDigit(d []int, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += d[i] * w
+	}
+	if rest := sum % 11; rest >= 2 {
+		return 11 - rest
+	}
+	return 0
+}
+
+func validCPF(s string) bool {
+	d := digits(s)
+	if len(d) != 11 || allEqual(d) {
+		return false
+	}
+	return checkDigit(d, []int{10, 9, 8, 7, 6, 5, 4, 3, 2}) == d[9] &&
+		checkDigit(d, []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}) == d[10]
+}
+
+func validCNPJ(s string) bool {
+	d := digits(s)
+	if len(d) != 14 || allEqual(d) {
+		return false
+	}
+	return checkDigit(d, []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == d[12] &&
+		checkDigit(d, []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == d[13]
+}
@@ -0,0 +1,146 @@
+package logzum_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type customer struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Document string   `json:"document"`
+	Password string   `json:"password"`
+	Cards    []string `json:"cards"`
+}
+
+func newRedactedHook(t *testing.T, redaction logzum.RedactionConfig) (logrus.Hook, <-chan []byte) {
+	return newTestHook(t, func(config *logzum.Config) { config.Redaction = &redaction })
+}
+
+func TestRedactKeys(t *testing.T) {
+	h, lines := newRedactedHook(t, logzum.DefaultRedactionConfig())
+
+	data := logrus.Fields{"password": "hunter2", "Token": "abc", "cpf": 12345678909, "user": "bob"}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "login", Data: data, Level: logrus.InfoLevel}))
+
+	line := receive(t, lines)
+	assert.NotContains(t, line, "password")
+	assert.Equal(t, logzum.DefaultRedactedValue, line["Token"])
+	assert.Equal(t, logzum.DefaultRedactedValue, line["cpf"])
+	assert.Equal(t, "bob", line["user"])
+	assert.Equal(t, "foo", line["bztoken"])
+	assert.Equal(t, "hunter2", data["password"], "the logged fields must not be changed")
+}
+
+func TestRedactValues(t *testing.T) {
+	h, lines := newRedactedHook(t, logzum.DefaultRedactionConfig())
+
+	data := logrus.Fields{
+		"cpf_formatted": "cpf 529.982.247-25 found",
+		"cnpj":          "11.222.333/0001-81",
+		"card":          "4111 1111 1111 1111",
+		"not_card":      "4111 1111 1111 1112",
+		"order":         "123456789",
+		"error":         errors.New("user john@example.com not found"),
+	}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "mail to john@example.com", Data: data, Level: logrus.InfoLevel}))
+
+	line := receive(t, lines)
+	assert.Equal(t, "mail to [REDACTED]", line["message"])
+	assert.Equal(t, "cpf [REDACTED] found", line["cpf_formatted"])
+	assert.Equal(t, "[REDACTED]", line["cnpj"])
+	assert.Equal(t, "[REDACTED]", line["card"])
+	assert.Equal(t, "4111 1111 1111 1112", line["not_card"])
+	assert.Equal(t, "123456789", line["order"])
+	assert.Equal(t, "user [REDACTED] not found", line["error"])
+}
+
+type document string
+
+type contact struct {
+	email string
+}
+
+func (c contact) String() string {
+	return "contact " + c.email
+}
+
+func TestRedactNamedTypes(t *testing.T) {
+	h, lines := newRedactedHook(t, logzum.DefaultRedactionConfig())
+
+	data := logrus.Fields{
+		"document": document("529.982.247-25"),
+		"contact":  contact{email: "john@example.com"},
+	}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "signup", Data: data, Level: logrus.InfoLevel}))
+
+	line := receive(t, lines)
+	assert.Equal(t, "[REDACTED]", line["document"])
+	assert.Equal(t, "contact [REDACTED]", line["contact"])
+}
+
+func TestRedactRecursive(t *testing.T) {
+	h, lines := newRedactedHook(t, logzum.DefaultRedactionConfig())
+
+	c := &customer{
+		Name:     "John",
+		Email:    "john@example.com",
+		Document: "52998224725",
+		Password: "hunter2",
+		Cards:    []string{"5555555555554444"},
+	}
+	data := logrus.Fields{
+		"customer": c,
+		"request":  map[string]interface{}{"headers": map[string]string{"token": "abc"}},
+	}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "checkout", Data: data, Level: logrus.InfoLevel}))
+
+	line := receive(t, lines)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "John",
+		"email":    "[REDACTED]",
+		"document": "[REDACTED]",
+		"cards":    []interface{}{"[REDACTED]"},
+	}, line["customer"])
+	assert.Equal(t, map[string]interface{}{
+		"headers": map[string]interface{}{"token": "[REDACTED]"},
+	}, line["request"])
+	assert.Equal(t, "hunter2", c.Password)
+}
+
+func TestRedactHash(t *testing.T) {
+	config := logzum.RedactionConfig{
+		Keys:    []logzum.KeyRule{{Key: "user_id", Action: logzum.RedactHash}},
+		Values:  []logzum.ValueRule{{Pattern: logzum.EmailRule.Pattern, Action: logzum.RedactHash}},
+		HashKey: []byte("secret"),
+	}
+	h, lines := newRedactedHook(t, config)
+
+	for i := 0; i < 2; i++ {
+		data := logrus.Fields{"user_id": "42", "email": "john@example.com"}
+		require.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: data, Level: logrus.InfoLevel}))
+	}
+	other := logrus.Fields{"user_id": "43", "email": "mary@example.com"}
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: other, Level: logrus.InfoLevel}))
+
+	first, second, third := receive(t, lines), receive(t, lines), receive(t, lines)
+	assert.Regexp(t, "^hmac:[0-9a-f]{64}$", first["user_id"])
+	assert.Regexp(t, "^hmac:[0-9a-f]{64}$", first["email"])
+	assert.Equal(t, first["user_id"], second["user_id"], "hashes must be correlatable")
+	assert.Equal(t, first["email"], second["email"])
+	assert.NotEqual(t, first["user_id"], third["user_id"])
+	assert.NotEqual(t, first["email"], third["email"])
+}
+
+func TestRedactActionUnmarshalText(t *testing.T) {
+	var a logzum.RedactAction
+	require.NoError(t, a.UnmarshalText([]byte("HASH")))
+	assert.Equal(t, logzum.RedactHash, a)
+	assert.Equal(t, "hash", a.String())
+	assert.Error(t, a.UnmarshalText([]byte("shred")))
+}
@@ -0,0 +1,76 @@
+package logzum
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Reload loads the YAML config file at path into the hook, the current configs are kept if it is invalid.
+func Reload(h Hook, path string) error {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return h.Reconfigure(config)
+}
+
+// ReloadOnSignal reloads the config file at path whenever one of signals is received, SIGHUP by default,
+// until stop is called.
+func ReloadOnSignal(h Hook, path string, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, signals...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigC:
+				if err := Reload(h, path); err != nil {
+					log.Printf("BurzumLogs: unable to reload config: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigC)
+		close(done)
+	}
+}
+
+// WatchConfig reloads the config file at path whenever its modification time or size changes, checking it every
+// interval until stop is called.
+func WatchConfig(h Hook, path string, interval time.Duration) (stop func()) {
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+					continue
+				}
+				last = info
+				if err := Reload(h, path); err != nil {
+					log.Printf("BurzumLogs: unable to reload config: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
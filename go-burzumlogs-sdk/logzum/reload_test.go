@@ -0,0 +1,231 @@
+package logzum_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/common/logging"
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconfigure(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) { config.MinLevel = logrus.InfoLevel })
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "before", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+	assert.Equal(t, "before", receive(t, lines)["message"])
+
+	host, moved := newTestServer(t)
+	config := logzum.DefaultConfig
+	config.Host = host
+	config.MinLevel = logrus.DebugLevel
+	config.Fields = map[string]interface{}{"app": "checkout"}
+	require.NoError(t, h.Reconfigure(config))
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "after", Data: logrus.Fields{}, Level: logrus.DebugLevel}))
+	line := receive(t, moved)
+	assert.Equal(t, "after", line["message"])
+	assert.Equal(t, "checkout", line["app"])
+	assert.Equal(t, "foo", line["bztoken"], "an empty token keeps the current one")
+}
+
+// gatedTransport refuses to connect until open is closed, so the lines stay queued.
+type gatedTransport struct {
+	open chan struct{}
+}
+
+func (t gatedTransport) Dial(config logzum.Config) (io.WriteCloser, error) {
+	select {
+	case <-t.open:
+		return net.Dial("tcp", config.Host)
+	default:
+		return nil, errors.New("gated")
+	}
+}
+
+func TestReconfigureToSinkKeepsQueuedLines(t *testing.T) {
+	host, lines := newTestServer(t)
+	gate := gatedTransport{open: make(chan struct{})}
+	config := logzum.DefaultConfig
+	config.Host = host
+	config.Transport = gate
+	config.MaxRetries = 1000
+	config.RetryDelay = 5 * time.Millisecond
+	h, _ := logzum.NewWithConfig("foo", config)
+	defer h.Close()
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "queued", Data: logrus.Fields{}}))
+
+	loki := newFakeLoki(t)
+	defer loki.Close()
+	config = logzum.Config{MaxRetries: 1, Loki: &logzum.LokiConfig{URL: loki.URL, BatchTimeout: time.Hour}}
+	require.NoError(t, h.Reconfigure(config))
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "after", Data: logrus.Fields{}}))
+
+	close(gate.open)
+	assert.Equal(t, "queued", receive(t, lines)["message"], "the queued lines are sent to their endpoint")
+	require.NoError(t, h.Flush(2*time.Second))
+	assert.Contains(t, receivePush(t, loki.pushes)[0].Entries[0].Line, "after")
+}
+
+func TestReconfigureInvalid(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) {})
+
+	config := logzum.DefaultConfig
+	config.Host = "localhost"
+	config.MinLevel = logrus.PanicLevel
+	assert.Error(t, h.Reconfigure(config))
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "kept", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+	assert.Equal(t, "kept", receive(t, lines)["message"], "an invalid config must not be applied")
+}
+
+func TestReconfigureFlushesDedup(t *testing.T) {
+	host, lines := newTestServer(t)
+	config := logzum.DefaultConfig
+	config.Host = host
+	config.Dedup = &logzum.DedupConfig{Window: time.Hour}
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "held", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+
+	config.Dedup = nil
+	require.NoError(t, h.Reconfigure(config))
+	assert.Equal(t, "held", receive(t, lines)["message"], "entries held by the previous configs must be sent")
+}
+
+func writeHostConfig(t *testing.T, path, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestWatchConfig(t *testing.T) {
+	host, lines := newTestServer(t)
+	dir, err := ioutil.TempDir("", "logzum")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logzum.yaml")
+	writeHostConfig(t, path, "host: "+host+"\nmin-level: info\n")
+
+	config, err := logzum.LoadConfig(path)
+	require.NoError(t, err)
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+
+	stop := logzum.WatchConfig(h, path, 10*time.Millisecond)
+	defer stop()
+
+	writeHostConfig(t, path, "host: "+host+"\nmin-level: debug\nfields: {config: v2}\n")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, h.Fire(&logrus.Entry{Message: "probe", Data: logrus.Fields{}, Level: logrus.DebugLevel}))
+		select {
+		case raw := <-lines:
+			assert.Equal(t, "v2", decode(t, raw)["config"])
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatal("config was not reloaded")
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	host, lines := newTestServer(t)
+	dir, err := ioutil.TempDir("", "logzum")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logzum.yaml")
+	writeHostConfig(t, path, "host: "+host+"\n")
+
+	config, err := logzum.LoadConfig(path)
+	require.NoError(t, err)
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+
+	stop := logzum.ReloadOnSignal(h, path)
+	defer stop()
+
+	writeHostConfig(t, path, "host: "+host+"\nfields: {config: v2}\n")
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(syscall.SIGHUP))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, h.Fire(&logrus.Entry{Message: "probe", Data: logrus.Fields{}, Level: logrus.InfoLevel}))
+		if line := receive(t, lines); line["config"] == "v2" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("config was not reloaded")
+}
+
+func TestSetLevel(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) { config.MinLevel = logrus.WarnLevel })
+
+	require.NoError(t, h.SetLevel(logrus.DebugLevel))
+	assert.Equal(t, logrus.DebugLevel, h.Level())
+
+	require.NoError(t, h.Fire(&logrus.Entry{Message: "debug", Data: logrus.Fields{}, Level: logrus.DebugLevel}))
+	assert.Equal(t, "debug", receive(t, lines)["message"])
+
+	assert.Error(t, h.SetLevel(logrus.Level(42)))
+	assert.Equal(t, logrus.DebugLevel, h.Level())
+}
+
+func TestLoweredLogger(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) { config.MinLevel = logrus.InfoLevel })
+	defer h.Close()
+	logger := &logrus.Logger{Out: ioutil.Discard, Formatter: &logrus.JSONFormatter{}, Hooks: make(logrus.LevelHooks), Level: logrus.InfoLevel}
+	logger.AddHook(h)
+
+	logger.Debug("skipped")
+	bzlogging.WithLevel(logrus.NewEntry(logger), logrus.DebugLevel).Debug("lowered")
+	bzlogging.WithLevel(logrus.NewEntry(logger), logrus.DebugLevel).Trace("too verbose")
+	logger.Info("info")
+
+	assert.Equal(t, "lowered", receive(t, lines)["message"], "the lowered entries bypass MinLevel")
+	assert.Equal(t, "info", receive(t, lines)["message"])
+}
+
+func TestLevelControlAudit(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) { config.MinLevel = logrus.InfoLevel })
+	defer h.Close()
+	logger := &logrus.Logger{Out: ioutil.Discard, Formatter: &logrus.JSONFormatter{}, Hooks: make(logrus.LevelHooks), Level: logrus.InfoLevel}
+	logger.AddHook(h)
+
+	_, err := bzlogging.NewLevelControl(logger, h).SetLevel("alice", logrus.ErrorLevel, 0)
+	require.NoError(t, err)
+	assert.Equal(t, logrus.ErrorLevel, h.Level())
+
+	line := receive(t, lines)
+	assert.Equal(t, "log level changed", line["message"], "the audit entry bypasses the quieter level")
+	assert.Equal(t, "warning", line["level"])
+	assert.Equal(t, "alice", line["audit.actor"])
+}
+
+func TestSetLevelKeepsStages(t *testing.T) {
+	// a negative MaxFieldBytes is refused by Validate, SetLevel must not validate nor rebuild the configs
+	h, lines := newTestHook(t, func(config *logzum.Config) {
+		config.MaxFieldBytes = -1
+		config.Sampling = &logzum.SamplingConfig{Interval: time.Hour, First: 1}
+	})
+
+	fire(t, h, logrus.InfoLevel, "sampled")
+	require.NoError(t, h.SetLevel(logrus.InfoLevel))
+	fire(t, h, logrus.InfoLevel, "sampled")
+	fire(t, h, logrus.InfoLevel, "other")
+
+	assert.Equal(t, "sampled", receive(t, lines)["message"])
+	assert.Equal(t, "other", receive(t, lines)["message"], "the sampler state is kept")
+}
@@ -0,0 +1,175 @@
+package logzum
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// SampledDroppedField is the log field reporting how many entries of the same key were suppressed
+	// by the sampler since the last emitted one.
+	SampledDroppedField = "sampled.dropped"
+)
+
+//SamplingConfig holds the sampler configs, each part is disabled by its zero value
+type SamplingConfig struct {
+	// Interval is the period of the First/Thereafter counters and of the level budgets, one second by default.
+	Interval time.Duration `yaml:"interval"`
+	// First entries of each key are emitted per interval, then every Thereafter-th one.
+	First      int `yaml:"first"`
+	Thereafter int `yaml:"thereafter"`
+	// Rate is the number of entries per second emitted for each key, in bursts of up to Burst entries.
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	// LevelBudgets is the number of entries emitted for each level per interval.
+	LevelBudgets map[logrus.Level]int `yaml:"-"`
+	// Key groups the entries, DefaultSamplingKey is used if nil.
+	Key func(entry *logrus.Entry) string `yaml:"-"`
+	// MaxKeys is the maximum number of keys tracked, DefaultSamplingMaxKeys by default. The entries of the keys
+	// above it are sampled together, as if they had the same key.
+	MaxKeys int `yaml:"max-keys"`
+}
+
+// DefaultSamplingMaxKeys is the number of keys tracked by the sampler when MaxKeys is not set.
+const DefaultSamplingMaxKeys = 10000
+
+// DefaultSamplingKey groups the entries by level and message.
+func DefaultSamplingKey(entry *logrus.Entry) string {
+	return entry.Level.String() + ":" + entry.Message
+}
+
+type sampleCounter struct {
+	count   int
+	dropped int
+	tokens  float64
+	last    time.Time
+	seen    time.Time
+}
+
+type sampler struct {
+	mu sync.Mutex
+
+	config SamplingConfig
+
+	reset time.Time
+
+	keys map[string]*sampleCounter
+
+	// overflow is the counter shared by the keys above MaxKeys
+	overflow *sampleCounter
+
+	levels map[logrus.Level]int
+}
+
+func newSampler(config SamplingConfig) *sampler {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	if config.Key == nil {
+		config.Key = DefaultSamplingKey
+	}
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = DefaultSamplingMaxKeys
+	}
+	if config.Rate > 0 && config.Burst < 1 {
+		config.Burst = 1
+		if config.Rate > 1 {
+			config.Burst = int(config.Rate)
+		}
+	}
+	return &sampler{
+		config: config,
+		keys:   make(map[string]*sampleCounter),
+		levels: make(map[logrus.Level]int),
+	}
+}
+
+// sample reports whether entry must be emitted and, if so, how many entries of its key were dropped before it.
+func (s *sampler) sample(entry *logrus.Entry, now time.Time) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !now.Before(s.reset) {
+		s.rollover(now)
+	}
+
+	c := s.counter(s.config.Key(entry), now)
+	c.seen = now
+
+	if !s.allow(c, entry.Level, now) {
+		c.dropped++
+		return false, 0
+	}
+	dropped := c.dropped
+	c.dropped = 0
+	return true, dropped
+}
+
+// counter returns the counter of key, or the overflow one when MaxKeys are already tracked.
+func (s *sampler) counter(key string, now time.Time) *sampleCounter {
+	if c, ok := s.keys[key]; ok {
+		return c
+	}
+	c := &sampleCounter{tokens: float64(s.config.Burst), last: now}
+	if len(s.keys) < s.config.MaxKeys {
+		s.keys[key] = c
+		return c
+	}
+	if s.overflow == nil {
+		s.overflow = c
+	}
+	return s.overflow
+}
+
+func (s *sampler) allow(c *sampleCounter, level logrus.Level, now time.Time) bool {
+	c.count++
+	if s.config.First > 0 || s.config.Thereafter > 0 {
+		if n := c.count - s.config.First; n > 0 && (s.config.Thereafter <= 0 || n%s.config.Thereafter != 0) {
+			return false
+		}
+	}
+
+	if s.config.Rate > 0 {
+		c.tokens += now.Sub(c.last).Seconds() * s.config.Rate
+		if max := float64(s.config.Burst); c.tokens > max {
+			c.tokens = max
+		}
+		c.last = now
+		if c.tokens < 1 {
+			return false
+		}
+		c.tokens--
+	}
+
+	if budget, ok := s.config.LevelBudgets[level]; ok {
+		if s.levels[level] >= budget {
+			return false
+		}
+		s.levels[level]++
+	}
+	return true
+}
+
+// rollover starts a new interval, forgetting the keys idle during the last one, along with the number of their
+// entries dropped since the last emitted one.
+func (s *sampler) rollover(now time.Time) {
+	idle := s.reset.Add(-s.config.Interval)
+	for key, c := range s.keys {
+		if c.seen.Before(idle) {
+			delete(s.keys, key)
+			continue
+		}
+		c.count = 0
+	}
+	if s.overflow != nil {
+		if s.overflow.seen.Before(idle) {
+			s.overflow = nil
+		} else {
+			s.overflow.count = 0
+		}
+	}
+	s.levels = make(map[logrus.Level]int)
+	s.reset = now.Add(s.config.Interval)
+}
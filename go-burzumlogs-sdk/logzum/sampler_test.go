@@ -0,0 +1,129 @@
+package logzum_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSampledHook(t *testing.T, sampling logzum.SamplingConfig) (logrus.Hook, <-chan []byte) {
+	return newTestHook(t, func(config *logzum.Config) { config.Sampling = &sampling })
+}
+
+func fire(t *testing.T, h logrus.Hook, level logrus.Level, msg string) {
+	require.NoError(t, h.Fire(&logrus.Entry{Message: msg, Data: logrus.Fields{}, Level: level}))
+}
+
+func TestSamplingFirstThereafter(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{Interval: time.Hour, First: 2, Thereafter: 3})
+
+	for i := 0; i < 8; i++ {
+		fire(t, h, logrus.InfoLevel, "hot path")
+	}
+	fire(t, h, logrus.InfoLevel, "other")
+
+	var dropped []interface{}
+	for i := 0; i < 4; i++ {
+		line := receive(t, lines)
+		assert.Equal(t, "hot path", line["message"])
+		dropped = append(dropped, line[logzum.SampledDroppedField])
+	}
+	assert.Equal(t, []interface{}{nil, nil, float64(2), float64(2)}, dropped)
+
+	line := receive(t, lines)
+	assert.Equal(t, "other", line["message"], "keys must be sampled independently")
+	assert.NotContains(t, line, logzum.SampledDroppedField)
+}
+
+func TestSamplingRate(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{Rate: 0.001, Burst: 2})
+
+	for i := 0; i < 5; i++ {
+		fire(t, h, logrus.ErrorLevel, "flood")
+	}
+	fire(t, h, logrus.ErrorLevel, "done")
+
+	assert.Equal(t, "flood", receive(t, lines)["message"])
+	assert.Equal(t, "flood", receive(t, lines)["message"])
+	assert.Equal(t, "done", receive(t, lines)["message"])
+}
+
+func TestSamplingLevelBudget(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{
+		Interval:     time.Hour,
+		LevelBudgets: map[logrus.Level]int{logrus.DebugLevel: 1},
+	})
+
+	fire(t, h, logrus.DebugLevel, "first")
+	fire(t, h, logrus.DebugLevel, "second")
+	fire(t, h, logrus.InfoLevel, "info")
+
+	assert.Equal(t, "first", receive(t, lines)["message"])
+	assert.Equal(t, "info", receive(t, lines)["message"])
+}
+
+func TestSamplingCustomKey(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{
+		Interval: time.Hour,
+		First:    1,
+		Key:      func(entry *logrus.Entry) string { return entry.Level.String() },
+	})
+
+	fire(t, h, logrus.WarnLevel, "a")
+	fire(t, h, logrus.WarnLevel, "b")
+	fire(t, h, logrus.InfoLevel, "c")
+
+	assert.Equal(t, "a", receive(t, lines)["message"])
+	assert.Equal(t, "c", receive(t, lines)["message"])
+}
+
+func TestSamplingMaxKeys(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{Interval: time.Hour, First: 1, MaxKeys: 1})
+
+	fire(t, h, logrus.InfoLevel, "tracked")
+	fire(t, h, logrus.InfoLevel, "a")
+	fire(t, h, logrus.InfoLevel, "b")
+	fire(t, h, logrus.InfoLevel, "tracked")
+	fire(t, h, logrus.InfoLevel, "last")
+
+	assert.Equal(t, "tracked", receive(t, lines)["message"])
+	assert.Equal(t, "a", receive(t, lines)["message"], "the keys above MaxKeys share one counter")
+	select {
+	case line := <-lines:
+		t.Fatalf("unexpected line %s", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSamplingForgetsIdleKeys(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{Interval: 20 * time.Millisecond, First: 1})
+
+	fire(t, h, logrus.InfoLevel, "once")
+	fire(t, h, logrus.InfoLevel, "once")
+	assert.Equal(t, "once", receive(t, lines)["message"])
+
+	// the key is forgotten after an idle interval, even with dropped entries
+	time.Sleep(30 * time.Millisecond)
+	fire(t, h, logrus.InfoLevel, "other")
+	time.Sleep(30 * time.Millisecond)
+	fire(t, h, logrus.InfoLevel, "once")
+
+	assert.Equal(t, "other", receive(t, lines)["message"])
+	line := receive(t, lines)
+	assert.Equal(t, "once", line["message"])
+	assert.NotContains(t, line, logzum.SampledDroppedField)
+}
+
+func TestFireDoesNotChangeEntry(t *testing.T) {
+	h, lines := newSampledHook(t, logzum.SamplingConfig{})
+
+	entry := &logrus.Entry{Message: "hello", Data: logrus.Fields{"a": 1}, Level: logrus.InfoLevel}
+	require.NoError(t, h.Fire(entry))
+
+	assert.Equal(t, "foo", receive(t, lines)["bztoken"])
+	assert.Equal(t, logrus.Fields{"a": 1}, entry.Data)
+}
@@ -0,0 +1,77 @@
+package logzum
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrFlushTimeout is returned by Flush when the queue is not empty after its timeout.
+var ErrFlushTimeout = errors.New("logzum: flush timeout")
+
+//Stats holds the counters of the entries handled by a hook, since it was created
+type Stats struct {
+	// Host is the current endpoint.
+	Host string
+	// Queued is the number of lines waiting to be sent.
+	Queued uint64
+	// Sent is the number of lines written to the endpoint.
+	Sent uint64
+	// Dropped is the number of lines skipped because the queue was full.
+	Dropped uint64
+	// Failed is the number of lines given up after MaxRetries.
+	Failed uint64
+}
+
+type counters struct {
+	// queued is the number of lines put in the queue
+	queued  uint64
+	sent    uint64
+	dropped uint64
+	failed  uint64
+}
+
+func (c *counters) done(sent bool) {
+	if sent {
+		atomic.AddUint64(&c.sent, 1)
+	} else {
+		atomic.AddUint64(&c.failed, 1)
+	}
+}
+
+func (h *hook) Stats() Stats {
+	sent := atomic.LoadUint64(&h.counters.sent)
+	failed := atomic.LoadUint64(&h.counters.failed)
+	queued := atomic.LoadUint64(&h.counters.queued)
+	p := h.pipeline()
+	host := p.config.Host
+	if p.sink != nil {
+		host = p.sink.endpoint()
+	}
+	return Stats{
+		Host:    host,
+		Queued:  queued - sent - failed,
+		Sent:    sent,
+		Dropped: atomic.LoadUint64(&h.counters.dropped),
+		Failed:  failed,
+	}
+}
+
+func (h *hook) Flush(timeout time.Duration) error {
+	p := h.pipeline()
+	if p.deduper != nil {
+		p.deduper.flush()
+	}
+	if p.sink != nil {
+		p.sink.flush()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for h.Stats().Queued > 0 {
+		if !time.Now().Before(deadline) {
+			return ErrFlushTimeout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
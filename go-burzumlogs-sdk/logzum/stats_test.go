@@ -0,0 +1,77 @@
+package logzum_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsAndFlush(t *testing.T) {
+	h, lines := newTestHook(t, func(config *logzum.Config) {
+		config.Dedup = &logzum.DedupConfig{Window: time.Hour}
+	})
+
+	fire(t, h, logrus.InfoLevel, "a")
+	fire(t, h, logrus.InfoLevel, "b")
+	require.NoError(t, h.Flush(time.Second))
+
+	assert.Equal(t, "a", receive(t, lines)["message"])
+	assert.Equal(t, "b", receive(t, lines)["message"], "held entries must be flushed")
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, uint64(0), stats.Queued)
+	assert.Equal(t, uint64(0), stats.Dropped)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestStatsFailedAndDropped(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	host := l.Addr().String()
+	l.Close()
+
+	config := logzum.DefaultConfig
+	config.Host = host
+	config.Buffersize = 1
+	config.MaxRetries = 1
+	config.RetryDelay = 0
+	h, err := logzum.NewWithConfig("foo", config)
+	require.Error(t, err)
+
+	for i := 0; i < 100; i++ {
+		fire(t, h, logrus.InfoLevel, "unreachable")
+	}
+	require.NoError(t, h.Flush(time.Second))
+
+	stats := h.Stats()
+	assert.Equal(t, host, stats.Host)
+	assert.True(t, stats.Failed > 0)
+	assert.True(t, stats.Dropped > 0)
+	assert.Equal(t, uint64(100), stats.Failed+stats.Dropped)
+}
+
+func TestStatsDefaultBuffersize(t *testing.T) {
+	config := logzum.DefaultConfig
+	config.Host = "localhost:5030"
+	config.Transport = gatedTransport{open: make(chan struct{})}
+	config.Buffersize = 0
+	config.MaxRetries = 1
+	config.RetryDelay = 0
+	h, err := logzum.NewWithConfig("foo", config)
+	require.Error(t, err)
+
+	for i := 0; i < 100; i++ {
+		fire(t, h, logrus.InfoLevel, "unreachable")
+	}
+	require.NoError(t, h.Flush(time.Second))
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(0), stats.Dropped, "a zero buffer size is the default one")
+	assert.Equal(t, uint64(100), stats.Failed)
+}
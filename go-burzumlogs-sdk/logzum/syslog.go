@@ -0,0 +1,223 @@
+package logzum
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSyslogFacility is the user-level facility.
+	DefaultSyslogFacility = 1
+
+	// DefaultSyslogSDID is the SD-ID of the structured data element carrying the fields, with the enterprise
+	// number reserved for documentation by RFC 5612.
+	DefaultSyslogSDID = "fields@32473"
+
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogFormatter formats the entries as RFC 5424 syslog messages, with the priority given by the facility and the
+// syslog severity of the level, and the fields in a structured data element.
+type SyslogFormatter struct {
+	// Facility is DefaultSyslogFacility if zero.
+	Facility int
+	// Hostname is the hostname of the machine by default.
+	Hostname string
+	// AppName is the name of the executable by default.
+	AppName string
+	// ProcID is the process ID by default.
+	ProcID string
+	// MsgID identifies the type of the messages, nil if empty.
+	MsgID string
+	// SDID is DefaultSyslogSDID if empty.
+	SDID string
+}
+
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	facility := f.Facility
+	if facility == 0 {
+		facility = DefaultSyslogFacility
+	}
+	hostname := f.Hostname
+	if hostname == "" {
+		hostname = defaultHostname()
+	}
+	appName := f.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	procID := f.ProcID
+	if procID == "" {
+		procID = strconv.Itoa(os.Getpid())
+	}
+	sdID := f.SDID
+	if sdID == "" {
+		sdID = DefaultSyslogSDID
+	}
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(facility*8 + SyslogSeverity(entry.Level)))
+	b.WriteString(">1 ")
+	b.WriteString(t.Format(syslogTimeFormat))
+	for _, field := range []struct {
+		value string
+		max   int
+	}{{hostname, 255}, {appName, 48}, {procID, 128}, {f.MsgID, 32}} {
+		b.WriteByte(' ')
+		b.WriteString(headerField(field.value, field.max))
+	}
+	b.WriteByte(' ')
+	writeStructuredData(&b, sdID, entry.Data)
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Message)
+	}
+	return b.Bytes(), nil
+}
+
+// headerField keeps the printable US-ASCII characters of value, up to max, the nil value "-" if none is left.
+func headerField(value string, max int) string {
+	clean := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(clean) > max {
+		clean = clean[:max]
+	}
+	if clean == "" {
+		return "-"
+	}
+	return clean
+}
+
+// sdName replaces the characters not allowed in a SD-NAME by `_`, keeping up to 32.
+func sdName(name string) string {
+	return headerField(strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name), 32)
+}
+
+func writeStructuredData(b *bytes.Buffer, sdID string, fields logrus.Fields) {
+	if len(fields) == 0 {
+		b.WriteByte('-')
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteByte('[')
+	b.WriteString(sdName(sdID))
+	for _, k := range keys {
+		b.WriteByte(' ')
+		b.WriteString(sdName(k))
+		b.WriteString(`="`)
+		b.WriteString(sdEscaper.Replace(fieldString(fields[k])))
+		b.WriteByte('"')
+	}
+	b.WriteByte(']')
+}
+
+// fieldString formats a field value as a string, as in GELF messages.
+func fieldString(v interface{}) string {
+	switch v := gelfValue(v).(type) {
+	case string:
+		return v
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SyslogUDPTransport sends each message in a UDP datagram, RFC 5426.
+type SyslogUDPTransport struct{}
+
+func (SyslogUDPTransport) Dial(config Config) (io.WriteCloser, error) {
+	conn, err := net.Dial("udp", config.Host)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogUDPConn{conn}, nil
+}
+
+type syslogUDPConn struct {
+	net.Conn
+}
+
+func (c *syslogUDPConn) Write(p []byte) (int, error) {
+	if _, err := c.Conn.Write(bytes.TrimRight(p, "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SyslogTCPTransport sends the messages over TCP with the octet-counting framing of RFC 6587.
+type SyslogTCPTransport struct{}
+
+func (SyslogTCPTransport) Dial(config Config) (io.WriteCloser, error) {
+	conn, err := dialTCP(config)
+	if err != nil {
+		return nil, err
+	}
+	return &octetCountingConn{conn}, nil
+}
+
+// SyslogTLSTransport sends the messages over TLS with the octet-counting framing, RFC 5425.
+type SyslogTLSTransport struct {
+	// TLSConfig verifies the server against the system roots by default.
+	TLSConfig *tls.Config
+}
+
+func (t SyslogTLSTransport) Dial(config Config) (io.WriteCloser, error) {
+	dialer := &net.Dialer{KeepAlive: config.KeepAlivePeriod}
+	conn, err := tls.DialWithDialer(dialer, "tcp", config.Host, t.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &octetCountingConn{conn}, nil
+}
+
+// octetCountingConn prefixes each message with its length and a space.
+type octetCountingConn struct {
+	net.Conn
+}
+
+func (c *octetCountingConn) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	framed := make([]byte, 0, len(msg)+8)
+	framed = strconv.AppendInt(framed, int64(len(msg)), 10)
+	framed = append(framed, ' ')
+	framed = append(framed, msg...)
+	if _, err := c.Conn.Write(framed); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
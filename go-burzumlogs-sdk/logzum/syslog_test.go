@@ -0,0 +1,202 @@
+package logzum_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luizalabs/burzumlogs-sdk/go-burzumlogs-sdk/logzum"
+)
+
+var syslogEntry = &logrus.Entry{
+	Time:    time.Date(2019, 10, 1, 10, 0, 0, 123456789, time.UTC),
+	Level:   logrus.ErrorLevel,
+	Message: "payment failed",
+	Data: logrus.Fields{
+		"http.status": 502,
+		"reason":      `card "declined" [soft]`,
+		"err":         errors.New(`a\\b`),
+		"bad key=x":   true,
+	},
+}
+
+func TestSyslogFormatter(t *testing.T) {
+	f := &logzum.SyslogFormatter{Facility: 16, Hostname: "api 1", AppName: "checkout", ProcID: "42", MsgID: "PAY"}
+	serialized, err := f.Format(syslogEntry)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`<131>1 2019-10-01T10:00:00.123456Z api1 checkout 42 PAY `+
+			`[fields@32473 bad_key_x="true" err="a\\\\b" http.status="502" reason="card \"declined\" [soft\]"] payment failed`,
+		string(serialized))
+
+	f = &logzum.SyslogFormatter{Hostname: "api", AppName: "checkout", ProcID: "1"}
+	serialized, err = f.Format(&logrus.Entry{Time: syslogEntry.Time, Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	require.NoError(t, err)
+	assert.Equal(t, `<14>1 2019-10-01T10:00:00.123456Z api checkout 1 - -`, string(serialized))
+
+	serialized, err = (&logzum.SyslogFormatter{}).Format(syslogEntry)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<11>1 \S+ \S+ \S+ `+strconv.Itoa(os.Getpid())+` - \[`, string(serialized))
+}
+
+func newSyslogHook(t *testing.T, host string, transport logzum.Transport) logzum.Hook {
+	config := logzum.DefaultConfig
+	config.Host = host
+	config.Formatter = &logzum.SyslogFormatter{Hostname: "api", AppName: "checkout", ProcID: "1"}
+	config.Transport = transport
+	h, err := logzum.NewWithConfig("foo", config)
+	require.NoError(t, err)
+	return h
+}
+
+func TestSyslogUDPTransport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	h := newSyslogHook(t, conn.LocalAddr().String(), logzum.SyslogUDPTransport{})
+	defer h.Close()
+	require.NoError(t, h.Fire(syslogEntry))
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<11>1 2019-10-01T10:00:00.123456Z api checkout 1 - \[fields@32473 .*\] payment failed$`, string(buf[:n]))
+	assert.NotContains(t, string(buf[:n]), "bztoken", "the token is sent to Burzum only")
+}
+
+// readOctetCounted reads the messages framed by RFC 6587 octet counting.
+func readOctetCounted(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(length[:len(length)-1])
+		if err != nil {
+			messages <- "invalid length " + length
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		messages <- string(msg)
+	}
+}
+
+func serveSyslog(t *testing.T, l net.Listener) <-chan string {
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		readOctetCounted(conn, messages)
+	}()
+	return messages
+}
+
+func receiveSyslog(t *testing.T, messages <-chan string) string {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a syslog message")
+		return ""
+	}
+}
+
+func TestSyslogTCPTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	messages := serveSyslog(t, l)
+
+	h := newSyslogHook(t, l.Addr().String(), logzum.SyslogTCPTransport{})
+	defer h.Close()
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.WarnLevel, Message: "multi\nline", Data: logrus.Fields{}}))
+	require.NoError(t, h.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "next", Data: logrus.Fields{}}))
+
+	assert.Regexp(t, `^<12>1 \S+ api checkout 1 - - multi\nline$`, receiveSyslog(t, messages))
+	assert.Regexp(t, `^<14>1 .* next$`, receiveSyslog(t, messages))
+}
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestSyslogTLSTransport(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	defer l.Close()
+	messages := serveSyslog(t, l)
+
+	h := newSyslogHook(t, l.Addr().String(), logzum.SyslogTLSTransport{TLSConfig: &tls.Config{RootCAs: pool}})
+	defer h.Close()
+	require.NoError(t, h.Fire(syslogEntry))
+
+	assert.Regexp(t, `^<11>1 .* payment failed$`, receiveSyslog(t, messages))
+}
+
+func TestSyslogTLSTransportUntrusted(t *testing.T) {
+	cert, _ := selfSignedCert(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+
+	config := logzum.DefaultConfig
+	config.Host = l.Addr().String()
+	_, err = logzum.SyslogTLSTransport{}.Dial(config)
+	assert.Error(t, err, "the certificate is not signed by the system roots")
+}
@@ -0,0 +1,72 @@
+package logzum
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Transport opens the connections the hook writes the formatted entries to, each Write carries a single entry.
+type Transport interface {
+	Dial(config Config) (io.WriteCloser, error)
+}
+
+// Transports are the transports selectable by name in YAML files and environment variables.
+var Transports = map[string]func() Transport{
+	"tcp":        func() Transport { return TCPTransport{} },
+	"gelf-udp":   func() Transport { return GELFUDPTransport{} },
+	"gelf-tcp":   func() Transport { return GELFTCPTransport{} },
+	"syslog-udp": func() Transport { return SyslogUDPTransport{} },
+	"syslog-tcp": func() Transport { return SyslogTCPTransport{} },
+	"syslog-tls": func() Transport { return SyslogTLSTransport{} },
+}
+
+// NewTransport returns a new transport registered in Transports.
+func NewTransport(name string) (Transport, error) {
+	t, ok := Transports[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(Transports))
+		for name := range Transports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown transport %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return t(), nil
+}
+
+// TCPTransport writes the formatted entries as they are to a TCP connection, the Burzum line protocol.
+type TCPTransport struct{}
+
+func (TCPTransport) Dial(config Config) (io.WriteCloser, error) {
+	return dialTCP(config)
+}
+
+func dialTCP(config Config) (*net.TCPConn, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", config.Host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(config.KeepAlivePeriod)
+	return conn, nil
+}
+
+// sameTransport reports whether a and b are known to be the same, transports that can't be compared are
+// assumed to be.
+func sameTransport(a, b Transport) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		return true
+	}
+	return a == b
+}